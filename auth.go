@@ -2,17 +2,22 @@ package nerf
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"fmt"
+	"html"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
-	"os"
 	"os/exec"
-	"os/signal"
 	"runtime"
+	"sync"
 	"time"
 
-	"github.com/google/go-github/github"
-	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"golang.org/x/oauth2"
 )
 
@@ -25,65 +30,184 @@ const authorizedHTML = `
 </html>
 `
 
-var server *http.Server
-var authCodeState = uuid.NewString()
+const failedHTML = `
+<html>
+<body>
+    <div style="width:400px; margin:0 auto; margin-top: 10%%; font-size: 30px; font-family: Times New Roman, Times, serif; text-align: center;">Not authorized.</div>
+    <div style="width:400px; margin:0 auto; font-size: 18px; font-family: Times New Roman, Times, serif; text-align: center;">%s</div>
+</body>
+</html>
+`
 
-// TokenSource defines Access Token for Github
-type TokenSource struct {
-	AccessToken string
+// authResult is the outcome of an attempt of the loopback OAuth flow
+type authResult struct {
+	token string
+	login string
+	err   error
 }
 
-// Token initializes Access Token for Github
-func (t *TokenSource) Token() (*oauth2.Token, error) {
-	token := &oauth2.Token{
-		AccessToken: t.AccessToken,
+// authAttempt is a single attempt of the loopback OAuth flow (RFC 8252)
+type authAttempt struct {
+	config   oauth2.Config
+	host     string
+	state    string
+	verifier string
+	result   chan authResult
+	once     sync.Once
+}
+
+// randomString returns a URL-safe string of n random bytes
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	return token, nil
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-func handleAuthMain(w http.ResponseWriter, r *http.Request) {
-	url := Cfg.OAuth.AuthCodeURL(authCodeState, oauth2.AccessTypeOnline)
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+// newAuthAttempt generates fresh state and PKCE verifier
+func newAuthAttempt(host string) (*authAttempt, error) {
+	state, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt := &authAttempt{
+		config:   *Cfg.OAuth,
+		host:     host,
+		state:    state,
+		verifier: verifier,
+		result:   make(chan authResult, 1),
+	}
+	attempt.config.RedirectURL = "http://" + host + "/callback"
+
+	return attempt, nil
 }
 
-func handleAuthDone(w http.ResponseWriter, r *http.Request) {
+// challenge derives PKCE code challenge using S256 method
+func (a *authAttempt) challenge() string {
+	sum := sha256.Sum256([]byte(a.verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// finish reports the result of the attempt, successful or not, only once
+func (a *authAttempt) finish(result authResult) {
+	a.once.Do(func() {
+		a.result <- result
+	})
+}
+
+// fail shows the error in the browser
+func (a *authAttempt) fail(w http.ResponseWriter, code int, message string) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write([]byte(authorizedHTML)); err != nil {
-		log.Fatalf("Failed writing response to a browser: %s\n", err)
+	w.WriteHeader(code)
+	fmt.Fprintf(w, failedHTML, html.EscapeString(message))
+}
+
+// checkRequest allows only GET requests addressed to the loopback listener
+func (a *authAttempt) checkRequest(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
 	}
-	p, _ := os.FindProcess(os.Getpid())
-	if err := p.Signal(os.Interrupt); err != nil {
-		log.Fatalf("Failed shutting down a web server: %s\n", err)
+	if r.Host != a.host {
+		http.Error(w, "Invalid host", http.StatusBadRequest)
+		return false
 	}
+
+	return true
 }
 
-func handleAuthCallback(w http.ResponseWriter, r *http.Request) {
-	state := r.FormValue("state")
-	if state != authCodeState {
-		fmt.Printf("Invalid oauth state, expected '%s', got '%s'\n", authCodeState, state)
-		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+func (a *authAttempt) handleAuthMain(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
 		return
 	}
+	if !a.checkRequest(w, r) {
+		return
+	}
+
+	url := a.config.AuthCodeURL(a.state,
+		oauth2.AccessTypeOnline,
+		oauth2.SetAuthURLParam("code_challenge", a.challenge()),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"))
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+}
 
-	code := r.FormValue("code")
-	token, err := Cfg.OAuth.Exchange(context.Background(), code)
+func (a *authAttempt) handleAuthCallback(w http.ResponseWriter, r *http.Request) {
+	if !a.checkRequest(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+
+	// A request with a wrong state is not from GitHub, keep waiting for the right one.
+	state := query.Get("state")
+	if subtle.ConstantTimeCompare([]byte(state), []byte(a.state)) != 1 {
+		a.fail(w, http.StatusBadRequest, "Invalid OAuth state, start signing in again from the application.")
+		return
+	}
+
+	if e := query.Get("error"); e != "" {
+		message := e
+		if description := query.Get("error_description"); description != "" {
+			message = fmt.Sprintf("%s: %s", e, description)
+		}
+		a.fail(w, http.StatusUnauthorized, message)
+		a.finish(authResult{err: fmt.Errorf("authorization failed: %s", message)})
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		a.fail(w, http.StatusBadRequest, "No authorization code received from GitHub.")
+		a.finish(authResult{err: fmt.Errorf("no authorization code received")})
+		return
+	}
+
+	token, err := a.config.Exchange(r.Context(), code,
+		oauth2.SetAuthURLParam("code_verifier", a.verifier))
 	if err != nil {
-		fmt.Printf("OAuth Exchange() failed with '%s'\n", err)
-		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		a.fail(w, http.StatusBadGateway, fmt.Sprintf("Can't exchange the authorization code: %s", err))
+		a.finish(authResult{err: fmt.Errorf("can't exchange the authorization code: %v", err)})
 		return
 	}
 
-	oauthClient := Cfg.OAuth.Client(context.Background(), token)
-	client := github.NewClient(oauthClient)
-	user, _, _ := client.Users.Get(context.Background(), "")
+	login, err := githubLogin(r.Context(), token.AccessToken)
+	if err != nil {
+		a.fail(w, http.StatusBadGateway, fmt.Sprintf("Can't retrieve your GitHub user: %s", err))
+		a.finish(authResult{err: fmt.Errorf("can't retrieve GitHub user: %v", err)})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(authorizedHTML))
+
+	a.finish(authResult{token: token.AccessToken, login: login})
+}
+
+// TokenSource defines Access Token for Github
+type TokenSource struct {
+	AccessToken string
+}
 
-	Cfg.Token = token.AccessToken
-	Cfg.Login = *user.Login
-	http.Redirect(w, r, "/done", http.StatusTemporaryRedirect)
+// Token initializes Access Token for Github
+func (t *TokenSource) Token() (*oauth2.Token, error) {
+	token := &oauth2.Token{
+		AccessToken: t.AccessToken,
+	}
+	return token, nil
 }
 
-func openBrowser(url string) error {
+// openBrowser opens url in the browser of the user
+var openBrowser = func(url string) error {
 	var err error
 
 	os := runtime.GOOS
@@ -102,45 +226,58 @@ func openBrowser(url string) error {
 	return err
 }
 
-// Auth handles OAuth authentication
-func Auth() {
+// Auth handles OAuth authentication in the browser
+func Auth(ctx context.Context) (string, string, error) {
+	lis, err := net.Listen("tcp", Cfg.ListenAddr)
+	if err != nil {
+		return "", "", err
+	}
+	host := lis.Addr().String()
+
+	attempt, err := newAuthAttempt(host)
+	if err != nil {
+		lis.Close()
+		return "", "", err
+	}
+
 	router := http.NewServeMux()
-	server = &http.Server{
-		Addr:     Cfg.ListenAddr,
+	router.HandleFunc("/", attempt.handleAuthMain)
+	router.HandleFunc("/callback", attempt.handleAuthCallback)
+
+	server := &http.Server{
 		Handler:  router,
-		ErrorLog: nil,
+		ErrorLog: log.New(ioutil.Discard, "", 0),
 	}
-
-	router.HandleFunc("/", handleAuthMain)
-	router.HandleFunc("/callback", handleAuthCallback)
-	router.HandleFunc("/done", handleAuthDone)
+	server.SetKeepAlivesEnabled(false)
 
 	go func() {
-		<-time.After(1000 * time.Millisecond)
-		err := openBrowser("http://" + Cfg.ListenAddr)
-		if err != nil {
-			fmt.Println(err)
+		if err := server.Serve(lis); err != http.ErrServerClosed {
+			attempt.finish(authResult{err: err})
 		}
 	}()
-
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt)
-
-	go func() {
-		server.SetKeepAlivesEnabled(false)
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			log.Fatalf("Failed starting a web server: %s\n", err)
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			Cfg.Logger.Debug("can't shut down OAuth web server", zap.Error(err))
 		}
 	}()
 
-	fmt.Printf("Your browser has been opened to visit:\n\thttp://%s\n", Cfg.ListenAddr)
-
-	<-done
+	if err := openBrowser("http://" + host); err != nil {
+		Cfg.Logger.Warn("can't open the browser", zap.Error(err))
+	}
+	fmt.Printf("Your browser has been opened to visit:\n\thttp://%s\n", host)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, Cfg.AuthTimeout)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Web server shutdown failed: %s\n", err)
+	select {
+	case result := <-attempt.result:
+		return result.token, result.login, result.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", "", fmt.Errorf("authorization timed out")
+		}
+		return "", "", ctx.Err()
 	}
 }
@@ -0,0 +1,345 @@
+package nerf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// serveOAuth stubs the authorization endpoint redirecting back with the code,
+// the token endpoint exchanging the code with the verifier, and the user endpoint of GitHub API
+func serveOAuth(t *testing.T, code string, verifier *string) {
+	var mutex sync.Mutex
+	var challenge string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/authorize", func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		challenge = r.FormValue("code_challenge")
+		mutex.Unlock()
+
+		redirect := r.FormValue("redirect_uri") + "?" + url.Values{"state": {r.FormValue("state")}, "code": {code}}.Encode()
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		// The verifier of an attempt started via the authorization endpoint matches its challenge.
+		valid := r.FormValue("code_verifier") == *verifier
+		mutex.Lock()
+		if challenge != "" {
+			sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+			valid = base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+		}
+		mutex.Unlock()
+
+		if r.FormValue("code") != code || !valid {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "bad_verification_code"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "secret", "token_type": "bearer"}`))
+	})
+	mux.HandleFunc("/api/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"login": "alice"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	oauth, apiURL := Cfg.OAuth, githubAPIURL
+	t.Cleanup(func() {
+		Cfg.OAuth, githubAPIURL = oauth, apiURL
+	})
+
+	Cfg.OAuth = &oauth2.Config{
+		ClientID: "client",
+		Scopes:   []string{"user:email"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  server.URL + "/login/oauth/authorize",
+			TokenURL: server.URL + "/login/oauth/access_token",
+		},
+	}
+	githubAPIURL = server.URL + "/api/"
+}
+
+func TestNewAuthAttempt(t *testing.T) {
+	serveOAuth(t, "", new(string))
+
+	first, err := newAuthAttempt("127.0.0.1:1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newAuthAttempt("127.0.0.1:1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.state == second.state || first.verifier == second.verifier {
+		t.Error("state or PKCE verifier reused between attempts")
+	}
+	// RFC 7636 requires 43 to 128 characters.
+	if n := len(first.verifier); n < 43 || n > 128 {
+		t.Errorf("verifier has %d characters", n)
+	}
+	if first.config.RedirectURL != "http://127.0.0.1:1234/callback" {
+		t.Errorf("RedirectURL = %s", first.config.RedirectURL)
+	}
+	if Cfg.OAuth.RedirectURL != "" {
+		t.Error("the redirect URL of the attempt leaked into Cfg.OAuth")
+	}
+}
+
+func TestAuthMain(t *testing.T) {
+	serveOAuth(t, "", new(string))
+
+	attempt, err := newAuthAttempt("127.0.0.1:1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		host     string
+		wantCode int
+	}{
+		{name: "redirect", method: http.MethodGet, target: "/", host: "127.0.0.1:1234", wantCode: http.StatusTemporaryRedirect},
+		{name: "rebound host", method: http.MethodGet, target: "/", host: "evil.example.com", wantCode: http.StatusBadRequest},
+		{name: "post", method: http.MethodPost, target: "/", host: "127.0.0.1:1234", wantCode: http.StatusMethodNotAllowed},
+		{name: "other path", method: http.MethodGet, target: "/favicon.ico", host: "127.0.0.1:1234", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+
+			attempt.handleAuthMain(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if w.Code != http.StatusTemporaryRedirect {
+				return
+			}
+
+			location, err := url.Parse(w.Header().Get("Location"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := location.Query()
+			sum := sha256.Sum256([]byte(attempt.verifier))
+			want := map[string]string{
+				"state":                 attempt.state,
+				"code_challenge":        base64.RawURLEncoding.EncodeToString(sum[:]),
+				"code_challenge_method": "S256",
+				"redirect_uri":          "http://127.0.0.1:1234/callback",
+				"client_id":             "client",
+			}
+			for name, value := range want {
+				if got := query.Get(name); got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+			if query.Get("code_verifier") != "" {
+				t.Error("PKCE verifier sent to the browser")
+			}
+		})
+	}
+}
+
+func TestAuthCallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		query      func(a *authAttempt) url.Values
+		wantCode   int
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name: "authorized",
+			host: "127.0.0.1:1234",
+			query: func(a *authAttempt) url.Values {
+				return url.Values{"state": {a.state}, "code": {"code"}}
+			},
+			wantCode:   http.StatusOK,
+			wantResult: true,
+		},
+		{
+			// A forged request must not end the attempt.
+			name: "wrong state",
+			host: "127.0.0.1:1234",
+			query: func(a *authAttempt) url.Values {
+				return url.Values{"state": {"forged"}, "code": {"code"}}
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "no state",
+			host: "127.0.0.1:1234",
+			query: func(a *authAttempt) url.Values {
+				return url.Values{"code": {"code"}}
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "rebound host",
+			host: "evil.example.com:1234",
+			query: func(a *authAttempt) url.Values {
+				return url.Values{"state": {a.state}, "code": {"code"}}
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "denied",
+			host: "127.0.0.1:1234",
+			query: func(a *authAttempt) url.Values {
+				return url.Values{"state": {a.state}, "error": {"access_denied"}}
+			},
+			wantCode:   http.StatusUnauthorized,
+			wantResult: true,
+			wantErr:    true,
+		},
+		{
+			name: "no code",
+			host: "127.0.0.1:1234",
+			query: func(a *authAttempt) url.Values {
+				return url.Values{"state": {a.state}}
+			},
+			wantCode:   http.StatusBadRequest,
+			wantResult: true,
+			wantErr:    true,
+		},
+		{
+			name: "wrong code",
+			host: "127.0.0.1:1234",
+			query: func(a *authAttempt) url.Values {
+				return url.Values{"state": {a.state}, "code": {"stolen"}}
+			},
+			wantCode:   http.StatusBadGateway,
+			wantResult: true,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := new(string)
+			serveOAuth(t, "code", verifier)
+
+			attempt, err := newAuthAttempt("127.0.0.1:1234")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The token endpoint accepts only the verifier of this attempt.
+			*verifier = attempt.verifier
+
+			r := httptest.NewRequest(http.MethodGet, "/callback?"+tt.query(attempt).Encode(), nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+
+			attempt.handleAuthCallback(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+
+			select {
+			case result := <-attempt.result:
+				if !tt.wantResult {
+					t.Fatalf("attempt finished with %+v", result)
+				}
+				if (result.err != nil) != tt.wantErr {
+					t.Errorf("result error = %v, wantErr %t", result.err, tt.wantErr)
+				}
+				if !tt.wantErr && (result.token != "secret" || result.login != "alice") {
+					t.Errorf("result = %+v, want secret, alice", result)
+				}
+			default:
+				if tt.wantResult {
+					t.Error("attempt not finished")
+				}
+			}
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		visit     bool
+		cancel    bool
+		wantErr   bool
+		wantLogin string
+	}{
+		{name: "authorized", visit: true, wantLogin: "alice"},
+		// Auth is called again in the same process after the first attempt.
+		{name: "authorized again", visit: true, wantLogin: "alice"},
+		{name: "canceled", cancel: true, wantErr: true},
+		{name: "timed out", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveOAuth(t, "code", new(string))
+
+			authTimeout, browser := Cfg.AuthTimeout, openBrowser
+			t.Cleanup(func() {
+				Cfg.AuthTimeout, openBrowser = authTimeout, browser
+			})
+			Cfg.AuthTimeout = 10 * time.Second
+			if !tt.visit && !tt.cancel {
+				Cfg.AuthTimeout = 10 * time.Millisecond
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var host string
+			openBrowser = func(u string) error {
+				host = u
+				if tt.cancel {
+					cancel()
+				}
+				if tt.visit {
+					go func() {
+						if resp, err := http.Get(u); err == nil {
+							resp.Body.Close()
+						}
+					}()
+				}
+				return nil
+			}
+
+			token, login, err := Auth(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Auth() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && (token != "secret" || login != tt.wantLogin) {
+				t.Errorf("Auth() = %s, %s, want secret, %s", token, login, tt.wantLogin)
+			}
+
+			// The loopback listener is closed once Auth returns.
+			if resp, err := http.Get(host); err == nil {
+				resp.Body.Close()
+				t.Errorf("%s is still served", host)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package nerf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// endpointsCache is the last discovery result stored on disk
+type endpointsCache struct {
+	ExpiresAt time.Time
+	Endpoints []Endpoint
+}
+
+// endpoints returns cached endpoints mapped by RemoteHost
+func (c *endpointsCache) endpoints() map[string]Endpoint {
+	endpoints := map[string]Endpoint{}
+
+	for _, e := range c.Endpoints {
+		endpoints[e.RemoteHost] = e
+	}
+
+	return endpoints
+}
+
+// saveEndpointsCache stores discovered endpoints until the DNS TTL expires
+func saveEndpointsCache(endpoints map[string]Endpoint, ttl time.Duration) error {
+	if Cfg.EndpointsCache == "" {
+		return nil
+	}
+
+	cache := endpointsCache{ExpiresAt: time.Now().Add(ttl)}
+	for _, e := range endpoints {
+		cache.Endpoints = append(cache.Endpoints, e)
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(Cfg.EndpointsCache, data, 0600)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to name
+func writeFileAtomic(name string, data []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(path.Dir(name), "."+path.Base(name)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// loadEndpointsCache loads the cached endpoints, if they expired no longer than grace ago
+func loadEndpointsCache(grace time.Duration) (*endpointsCache, error) {
+	if Cfg.EndpointsCache == "" {
+		return nil, fmt.Errorf("endpoints cache is disabled")
+	}
+
+	data, err := ioutil.ReadFile(Cfg.EndpointsCache)
+	if err != nil {
+		return nil, err
+	}
+
+	var cache endpointsCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("invalid endpoints cache: %s", err)
+	}
+
+	if len(cache.Endpoints) == 0 {
+		return nil, fmt.Errorf("endpoints cache is empty")
+	}
+
+	if time.Now().After(cache.ExpiresAt.Add(grace)) {
+		return nil, fmt.Errorf("endpoints cache expired at %s", cache.ExpiresAt)
+	}
+
+	return &cache, nil
+}
+
+// useCachedEndpoints replaces Cfg.Endpoints with the cached ones, if not expired
+func useCachedEndpoints(ctx context.Context) bool {
+	if len(Cfg.StaticEndpoints) > 0 {
+		return false
+	}
+
+	logger := Cfg.Logger.With(TraceFields(ctx)...)
+
+	cache, err := loadEndpointsCache(0)
+	if err != nil {
+		logger.Debug("not using cached endpoints", zap.Error(err))
+		return false
+	}
+
+	logger.Debug("using cached endpoints",
+		zap.Time("ExpiresAt", cache.ExpiresAt),
+		zap.Int("Endpoints", len(cache.Endpoints)))
+
+	endpointsMutex.Lock()
+	Cfg.Endpoints = cache.endpoints()
+	endpointsMutex.Unlock()
+
+	return true
+}
@@ -6,6 +6,11 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ton31337/nerf"
@@ -14,7 +19,7 @@ import (
 	"google.golang.org/grpc"
 )
 
-const UnixSockAddr = "/tmp/nerf.sock"
+var UnixSockAddr = nerf.ApiSocket()
 
 func main() {
 	var d net.Dialer
@@ -24,6 +29,93 @@ func main() {
 		"info",
 		"Set the logging level - values are 'debug', 'info', 'warn', and 'error'",
 	)
+	otlpEndpoint := flag.String(
+		"otlp-endpoint",
+		os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		"Set OTLP/gRPC collector address to export traces to. E.g.: 127.0.0.1:4317",
+	)
+	dnsResolvers := flag.String(
+		"dns-resolvers",
+		"system",
+		"Set comma-separated DNS resolvers for endpoint discovery, queried in the given order. "+
+			"E.g.: system,10.0.0.1:53,https://cloudflare-dns.com/dns-query",
+	)
+	staticEndpoints := flag.String(
+		"static-endpoints",
+		"",
+		"Set comma-separated endpoints to use instead of DNS discovery. "+
+			"E.g.: vpn1.example.com:9000=Lithuania,10.0.0.2=Netherlands",
+	)
+	endpointsCache := flag.String(
+		"endpoints-cache",
+		path.Join(nerf.NebulaDir(), "endpoints.json"),
+		"Set the file to cache discovered endpoints in. Empty disables caching",
+	)
+	endpointsCacheTTL := flag.Duration(
+		"endpoints-cache-ttl",
+		5*time.Minute,
+		"Set for how long cached endpoints are used if DNS resolvers do not report TTLs",
+	)
+	endpointsCacheGrace := flag.Duration(
+		"endpoints-cache-grace",
+		24*time.Hour,
+		"Set for how long expired cached endpoints are used if endpoint discovery fails",
+	)
+	probeSamples := flag.Int(
+		"probe-samples",
+		5,
+		"Set the number of pings sent to every endpoint to measure median RTT and jitter",
+	)
+	probeTimeout := flag.Duration(
+		"probe-timeout",
+		5*time.Second,
+		"Set the deadline for probing all the endpoints in parallel",
+	)
+	failoverThreshold := flag.Int(
+		"failover-threshold",
+		3,
+		"Set the number of consecutive failed health checks before switching to another endpoint",
+	)
+	failoverInterval := flag.Duration(
+		"failover-interval",
+		5*time.Second,
+		"Set the interval of health checks of the current endpoint",
+	)
+	failoverCooloff := flag.Duration(
+		"failover-cooloff",
+		5*time.Minute,
+		"Set for how long a failed endpoint is not used unless nothing else is reachable",
+	)
+	migrationInterval := flag.Duration(
+		"migration-interval",
+		5*time.Minute,
+		"Set the interval of re-probing endpoints while connected to migrate to a faster one. 0 disables migration",
+	)
+	migrationMargin := flag.Float64(
+		"migration-margin",
+		0.3,
+		"Set by how much another endpoint must be faster to migrate to it. E.g.: 0.3 means 30% faster",
+	)
+	reconnectJitter := flag.Duration(
+		"reconnect-jitter",
+		30*time.Second,
+		"Set the maximum random delay before reconnecting when the server announces a new config or teams change",
+	)
+	nebulaMaxRestarts := flag.Int(
+		"nebula-max-restarts",
+		5,
+		"Set how many times in a row Nebula is restarted after exiting unexpectedly before disconnecting",
+	)
+	nebulaStopTimeout := flag.Duration(
+		"nebula-stop-timeout",
+		10*time.Second,
+		"Set how long to wait for Nebula to exit on SIGTERM before killing it",
+	)
+	apiGroup := flag.String(
+		"group",
+		"nerf",
+		"Set the group whose members may use nerf-api. Empty allows every local user",
+	)
 	printUsage := flag.Bool("help", false, "Print command line usage")
 
 	flag.Parse()
@@ -47,18 +139,76 @@ func main() {
 	}.Build()
 
 	nerf.Cfg.Logger = logger
+	nerf.Cfg.EndpointsCache = *endpointsCache
+	nerf.Cfg.EndpointsCacheTTL = *endpointsCacheTTL
+	nerf.Cfg.EndpointsCacheGrace = *endpointsCacheGrace
+	nerf.Cfg.ProbeSamples = *probeSamples
+	nerf.Cfg.ProbeTimeout = *probeTimeout
+	nerf.Cfg.FailoverThreshold = *failoverThreshold
+	nerf.Cfg.FailoverInterval = *failoverInterval
+	nerf.Cfg.FailoverCooloff = *failoverCooloff
+	nerf.Cfg.MigrationInterval = *migrationInterval
+	nerf.Cfg.MigrationMargin = *migrationMargin
+	nerf.Cfg.ReconnectJitter = *reconnectJitter
+	nerf.Cfg.NebulaMaxRestarts = *nebulaMaxRestarts
+	nerf.Cfg.NebulaStopTimeout = *nebulaStopTimeout
+	nerf.Cfg.ApiGroup = *apiGroup
 
-	err := nerf.NebulaDownload()
+	shutdownTracing, err := nerf.InitTracing("nerf-api", *otlpEndpoint)
 	if err != nil {
-		if _, err := os.Stat(nerf.NebulaExecutable()); err != nil {
-			nerf.Cfg.Logger.Fatal("can't install Nebula", zap.Error(err))
-		}
+		nerf.Cfg.Logger.Fatal("can't initialize tracing", zap.Error(err))
+	}
+	defer func() {
+		_ = shutdownTracing(context.Background())
+	}()
+
+	nerf.Cfg.Resolver, err = nerf.NewResolver(strings.Split(*dnsResolvers, ","))
+	if err != nil {
+		nerf.Cfg.Logger.Fatal("can't configure DNS resolvers", zap.Error(err))
+	}
+
+	nerf.Cfg.StaticEndpoints, err = nerf.ParseStaticEndpoints(strings.Split(*staticEndpoints, ","))
+	if err != nil {
+		nerf.Cfg.Logger.Fatal("can't configure static endpoints", zap.Error(err))
+	}
+
+	if err = nerf.NebulaDownload(context.Background()); err != nil {
+		nerf.Cfg.Logger.Fatal("can't install Nebula", zap.Error(err))
 	}
 
 	defer func() {
 		_ = nerf.Cfg.Logger.Sync()
 	}()
 
+	gid := 0
+	if *apiGroup != "" {
+		group, err := user.LookupGroup(*apiGroup)
+		if err != nil {
+			nerf.Cfg.Logger.Fatal("can't find the group of nerf-api users, create it or set -group",
+				zap.String("Group", *apiGroup),
+				zap.Error(err))
+		}
+		gid, _ = strconv.Atoi(group.Gid)
+	}
+
+	// The socket lives in a directory only nerf-api can write to,
+	// thus nobody else can replace it. It's created by nerf-helper,
+	// unless nerf-api runs as root.
+	if err := os.MkdirAll(nerf.ApiSocketDir(), 0755); err != nil {
+		nerf.Cfg.Logger.Fatal("can't create the directory of UNIX socket, is nerf-helper running?", zap.Error(err))
+	}
+	if err := os.Chmod(nerf.ApiSocketDir(), 0755); err != nil {
+		nerf.Cfg.Logger.Fatal("can't set permissions for UNIX socket directory", zap.Error(err))
+	}
+
+	helperConn, err := grpc.Dial("unix:"+nerf.HelperSocket(), nerf.GRPCDialOptions()...)
+	if err != nil {
+		nerf.Cfg.Logger.Fatal("can't connect to nerf-helper", zap.Error(err))
+	}
+	defer helperConn.Close()
+
+	nerf.Cfg.Helper = nerf.NewHelperClient(helperConn)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -78,15 +228,27 @@ func main() {
 	}
 	defer lis.Close()
 
-	if os.Chmod(UnixSockAddr, 0777) != nil {
-		nerf.Cfg.Logger.Fatal("can't set write permissions for UNIX socket", zap.Error(err))
+	// Only root and the members of the group may connect. Callers are
+	// authorized by their peer credentials as well.
+	if err := os.Chown(UnixSockAddr, os.Getuid(), gid); err != nil {
+		nerf.Cfg.Logger.Fatal("can't change the owner of UNIX socket", zap.Error(err))
+	}
+	mode := os.FileMode(0660)
+	if *apiGroup == "" {
+		mode = 0666
+	}
+	if err := os.Chmod(UnixSockAddr, mode); err != nil {
+		nerf.Cfg.Logger.Fatal("can't set permissions for UNIX socket", zap.Error(err))
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(append(
+		nerf.GRPCServerOptions(nerf.Cfg.Logger),
+		grpc.Creds(nerf.PeerCredentials()),
+	)...)
 	nerf.RegisterApiServer(grpcServer, &nerf.Api{})
 
 	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
 		if err := grpcServer.Serve(lis); err != nil {
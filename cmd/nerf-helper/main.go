@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"os"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ton31337/nerf"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	logLevel := flag.String(
+		"log-level",
+		"info",
+		"Set the logging level - values are 'debug', 'info', 'warn', and 'error'",
+	)
+	helperUser := flag.String(
+		"user",
+		"nerf-api",
+		"Set the user nerf-api runs as. Only this user and root may use nerf-helper",
+	)
+	nebulaStopTimeout := flag.Duration(
+		"nebula-stop-timeout",
+		10*time.Second,
+		"Set how long to wait for Nebula to exit on SIGTERM before killing it, if nerf-api goes away",
+	)
+	dnsBackend := flag.String(
+		"dns-backend",
+		"auto",
+		"Set how name servers are configured on Linux - values are 'auto', 'systemd-resolved', "+
+			"'networkmanager', 'resolvconf', and 'resolv.conf'",
+	)
+	printUsage := flag.Bool("help", false, "Print command line usage")
+
+	flag.Parse()
+
+	if *printUsage {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	nerf.Cfg = nerf.NewConfig()
+
+	logger, _ := zap.Config{
+		Encoding:    "json",
+		Level:       zap.NewAtomicLevelAt(nerf.StringToLogLevel(*logLevel)),
+		OutputPaths: []string{"stdout"},
+		EncoderConfig: zapcore.EncoderConfig{
+			TimeKey:    "timestamp",
+			EncodeTime: zapcore.ISO8601TimeEncoder,
+			MessageKey: "message",
+		},
+	}.Build()
+
+	nerf.Cfg.Logger = logger
+	nerf.Cfg.HelperUser = *helperUser
+	nerf.Cfg.NebulaStopTimeout = *nebulaStopTimeout
+	nerf.Cfg.DNSBackend = *dnsBackend
+
+	defer func() {
+		_ = nerf.Cfg.Logger.Sync()
+	}()
+
+	if os.Geteuid() != 0 {
+		nerf.Cfg.Logger.Fatal("nerf-helper must run as root")
+	}
+
+	u, err := user.Lookup(*helperUser)
+	if err != nil {
+		nerf.Cfg.Logger.Fatal("can't find the user of nerf-api, create it or set -user",
+			zap.String("User", *helperUser),
+			zap.Error(err))
+	}
+	uid, _ := strconv.Atoi(u.Uid)
+	gid, _ := strconv.Atoi(u.Gid)
+
+	// nerf-api can't create directories in /run (/var/run on macOS) itself.
+	if err := os.MkdirAll(nerf.ApiSocketDir(), 0755); err != nil {
+		nerf.Cfg.Logger.Fatal("can't create the directory of nerf-api UNIX socket", zap.Error(err))
+	}
+	if err := os.Chown(nerf.ApiSocketDir(), uid, gid); err != nil {
+		nerf.Cfg.Logger.Fatal("can't change the owner of nerf-api UNIX socket directory", zap.Error(err))
+	}
+	if err := os.Chmod(nerf.ApiSocketDir(), 0755); err != nil {
+		nerf.Cfg.Logger.Fatal("can't set permissions for nerf-api UNIX socket directory", zap.Error(err))
+	}
+
+	// The socket and Nebula config live in a directory only root can write to.
+	if err := os.MkdirAll(nerf.HelperDir(), 0755); err != nil {
+		nerf.Cfg.Logger.Fatal("can't create the directory of UNIX socket", zap.Error(err))
+	}
+	if err := os.Chown(nerf.HelperDir(), 0, 0); err != nil {
+		nerf.Cfg.Logger.Fatal("can't change the owner of UNIX socket directory", zap.Error(err))
+	}
+	if err := os.Chmod(nerf.HelperDir(), 0755); err != nil {
+		nerf.Cfg.Logger.Fatal("can't set permissions for UNIX socket directory", zap.Error(err))
+	}
+
+	if err := os.RemoveAll(nerf.HelperSocket()); err != nil {
+		nerf.Cfg.Logger.Fatal("can't remove UNIX socket", zap.Error(err))
+	}
+
+	lis, err := net.Listen("unix", nerf.HelperSocket())
+	if err != nil {
+		nerf.Cfg.Logger.Fatal("can't listen on UNIX socket", zap.Error(err))
+	}
+	defer lis.Close()
+
+	// Only nerf-api (and root) may connect. Callers are
+	// authorized by their peer credentials as well.
+	if err := os.Chown(nerf.HelperSocket(), uid, 0); err != nil {
+		nerf.Cfg.Logger.Fatal("can't change the owner of UNIX socket", zap.Error(err))
+	}
+	if err := os.Chmod(nerf.HelperSocket(), 0600); err != nil {
+		nerf.Cfg.Logger.Fatal("can't set permissions for UNIX socket", zap.Error(err))
+	}
+
+	helper := &nerf.Helper{}
+
+	grpcServer := grpc.NewServer(append(
+		nerf.GRPCServerOptions(nerf.Cfg.Logger),
+		grpc.Creds(nerf.HelperCredentials()),
+	)...)
+	nerf.RegisterHelperServer(grpcServer, helper)
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			nerf.Cfg.Logger.Fatal("can't serve gRPC", zap.Error(err))
+		}
+	}()
+
+	<-done
+
+	helper.Stop()
+	grpcServer.Stop()
+}
@@ -1,20 +1,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ton31337/nerf"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-func startServer(lightHouse string) {
+func startServer(lightHouse string, port int, drainTimeout time.Duration, nebulaManifest string) {
 	if lightHouse == "" {
 		fmt.Println("-lighthouse flag must be set")
 		flag.Usage()
@@ -45,38 +49,110 @@ func startServer(lightHouse string) {
 
 	nerf.ServerCfg.Logger.Debug("Nerf server started", zap.String("lightHouse", lightHouse))
 
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		nerf.ServerCfg.Logger.Fatal("failed to listen gRPC server", zap.Error(err))
+	}
+
+	grpcServer := grpc.NewServer(nerf.GRPCServerOptions(nerf.ServerCfg.Logger)...)
+	nerf.RegisterServerServer(grpcServer, &nerf.Server{})
+	healthpb.RegisterHealthServer(grpcServer, nerf.ServerCfg.Health)
+
+	// gRPC server is started immediately, but reports NOT_SERVING
+	// until Teams are synced initially.
 	go func() {
+		syncTeams()
 		for range time.Tick(10 * time.Second) {
-			if (time.Now().Unix() - nerf.ServerCfg.Teams.UpdatedAt) > int64(time.Hour.Seconds()) {
-				nerf.ServerCfg.Teams.Mutex.Lock()
-				nerf.ServerCfg.Logger.Debug(
-					"begin-of-sync Github Teams with local cache")
-				nerf.ServerCfg.Teams.Sync()
-				nerf.ServerCfg.Logger.Debug(
-					"end-of-sync Github Teams with local cache")
-				nerf.ServerCfg.Teams.Mutex.Unlock()
-			}
+			syncTeams()
 		}
 	}()
 
-	// Start gRPC server only when Teams are synced initially.
-	for {
-		if nerf.ServerCfg.Teams != nil && !nerf.ServerCfg.Teams.Mutex.Locked() {
-			lis, err := net.Listen("tcp", fmt.Sprintf(":%d", 9000))
-			if err != nil {
-				nerf.ServerCfg.Logger.Fatal("failed to listen gRPC server", zap.Error(err))
-			}
+	go func() {
+		if err = grpcServer.Serve(lis); err != nil {
+			nerf.ServerCfg.Logger.Fatal("can't serve gRPC", zap.Error(err))
+		}
+	}()
 
-			grpcServer := grpc.NewServer()
-			nerf.RegisterServerServer(grpcServer, &nerf.Server{})
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
-			if err = grpcServer.Serve(lis); err != nil {
-				nerf.ServerCfg.Logger.Fatal("can't serve gRPC", zap.Error(err))
+	// SIGHUP tells connected clients to fetch a new config.yml for Nebula.
+	for sig := range done {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		if nebulaManifest != "" {
+			manifest, err := nerf.NebulaLoadManifest(nebulaManifest)
+			if err != nil {
+				nerf.ServerCfg.Logger.Error("can't reload Nebula manifest", zap.Error(err))
+			} else {
+				nerf.ServerCfg.SetNebulaManifest(manifest)
 			}
+		}
+		nerf.ServerCfg.Logger.Info("notifying clients about new config")
+		nerf.ServerCfg.Events.Publish("", nerf.NewEvent(
+			nerf.Event_CONFIG_AVAILABLE,
+			"New configuration is available, reconnecting",
+		))
+	}
 
-			break
+	drainServer(grpcServer, drainTimeout)
+}
+
+// drainServer waits until clients migrate, but no longer than drainTimeout
+func drainServer(grpcServer *grpc.Server, drainTimeout time.Duration) {
+	nerf.ServerCfg.Logger.Info("draining Nerf server", zap.Duration("drainTimeout", drainTimeout))
+	nerf.ServerCfg.Drain()
+
+	deadline := time.NewTimer(drainTimeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for clients := nerf.ServerCfg.Events.Subscribers(); clients > 0; clients = nerf.ServerCfg.Events.Subscribers() {
+		select {
+		case <-ticker.C:
+			continue
+		case <-deadline.C:
+			nerf.ServerCfg.Logger.Warn("drain timeout expired, stopping forcibly", zap.Int("Clients", clients))
+			grpcServer.Stop()
+			nerf.ServerCfg.Logger.Info("Nerf server stopped")
+			return
 		}
 	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	// Health watchers are long-lived streams, thus if some of them are still
+	// open after the timeout, stop the server forcibly.
+	select {
+	case <-stopped:
+	case <-deadline.C:
+		nerf.ServerCfg.Logger.Warn("drain timeout expired, stopping forcibly")
+		grpcServer.Stop()
+	}
+
+	nerf.ServerCfg.Logger.Info("Nerf server stopped")
+}
+
+func syncTeams() {
+	if (time.Now().Unix() - nerf.ServerCfg.Teams.UpdatedAt) <= int64(time.Hour.Seconds()) {
+		return
+	}
+
+	nerf.ServerCfg.Logger.Debug("begin-of-sync Github Teams with local cache")
+	if err := nerf.ServerCfg.Teams.Sync(); err != nil {
+		nerf.ServerCfg.Logger.Error("can't sync Github Teams", zap.Error(err))
+		return
+	}
+	nerf.ServerCfg.Logger.Debug("end-of-sync Github Teams with local cache")
+
+	nerf.ServerCfg.SetReady()
 }
 
 func main() {
@@ -91,6 +167,26 @@ func main() {
 		"info",
 		"Set the logging level - values are 'debug', 'info', 'warn', and 'error'",
 	)
+	otlpEndpoint := flag.String(
+		"otlp-endpoint",
+		os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		"Set OTLP/gRPC collector address to export traces to. E.g.: 127.0.0.1:4317",
+	)
+	port := flag.Int(
+		"port",
+		nerf.DefaultServerPort,
+		"Set the port for gRPC server, it must match the port advertised in DNS SRV record",
+	)
+	drainTimeout := flag.Duration(
+		"drain-timeout",
+		30*time.Second,
+		"Set how long to wait for clients to migrate and in-flight requests to finish on SIGTERM",
+	)
+	nebulaManifest := flag.String(
+		"nebula-manifest",
+		"",
+		"Set the path of Nebula releases manifest to require clients to upgrade to its minVersion. Reloaded on SIGHUP",
+	)
 	printUsage := flag.Bool("help", false, "Print command line usage")
 
 	flag.Parse()
@@ -114,11 +210,27 @@ func main() {
 	}.Build()
 
 	nerf.ServerCfg.Logger = logger
+
+	shutdownTracing, err := nerf.InitTracing("nerf-server", *otlpEndpoint)
+	if err != nil {
+		nerf.ServerCfg.Logger.Fatal("can't initialize tracing", zap.Error(err))
+	}
+	defer func() {
+		_ = shutdownTracing(context.Background())
+	}()
 	nerf.ServerCfg.GaidysUrl = *gaidysUrl
 
+	if *nebulaManifest != "" {
+		manifest, err := nerf.NebulaLoadManifest(*nebulaManifest)
+		if err != nil {
+			nerf.ServerCfg.Logger.Fatal("can't load Nebula manifest", zap.Error(err))
+		}
+		nerf.ServerCfg.SetNebulaManifest(manifest)
+	}
+
 	defer func() {
 		_ = nerf.ServerCfg.Logger.Sync()
 	}()
 
-	startServer(*lightHouse)
+	startServer(*lightHouse, *port, *drainTimeout, *nebulaManifest)
 }
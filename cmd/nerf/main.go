@@ -2,7 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/getlantern/systray"
@@ -11,19 +17,27 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
-const UnixSockAddr = "unix:/tmp/nerf.sock"
+var UnixSockAddr = "unix:" + nerf.ApiSocket()
 
-var mStatus, mRemoteIP, mConnect, mDisconnect, mQuitOrig *systray.MenuItem
+var mStatus, mRemoteIP, mEvent, mEndpoints, mAutomatic, mConnect, mDisconnect, mSignOut, mQuitOrig *systray.MenuItem
+var mEndpointItems []*systray.MenuItem
+var endpointHosts []string
+var endpointsMutex sync.Mutex
 var connectionTime time.Time
 var connectionTicker *time.Ticker
+var stopWatchingEvents context.CancelFunc
+var guiMutex sync.Mutex
+var guiState nerf.Status_State
+var cancelSignIn context.CancelFunc
 
 func grpcConnection() (*grpc.ClientConn, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	connection, err := grpc.DialContext(ctx, UnixSockAddr, grpc.WithInsecure())
+	connection, err := grpc.DialContext(ctx, UnixSockAddr, nerf.GRPCDialOptions()...)
 	if err != nil {
 		nerf.Cfg.Logger.Error("can't connect to gRPC UNIX socket", zap.Error(err))
 		nerf.Cfg.Connected = false
@@ -34,7 +48,63 @@ func grpcConnection() (*grpc.ClientConn, error) {
 }
 
 func main() {
+	deviceLogin := flag.Bool(
+		"device-login",
+		false,
+		"Sign in with GitHub by entering a code on another device, instead of opening the browser, and exit",
+	)
+	flag.Parse()
+
+	nerf.Cfg = nerf.NewConfig()
+
+	logger, _ := zap.Config{
+		Encoding:    "json",
+		Level:       zap.NewAtomicLevelAt(zapcore.DebugLevel),
+		OutputPaths: []string{"stdout"},
+		EncoderConfig: zapcore.EncoderConfig{
+			TimeKey:    "timestamp",
+			EncodeTime: zapcore.ISO8601TimeEncoder,
+			MessageKey: "message",
+		},
+	}.Build()
+
+	nerf.Cfg.Logger = logger
+
+	if *deviceLogin {
+		if err := signInDevice(); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't sign in: %s\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	shutdownTracing, err := nerf.InitTracing("nerf", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+	if err != nil {
+		nerf.Cfg.Logger.Error("can't initialize tracing", zap.Error(err))
+	}
+
 	systray.Run(onReady, nil)
+
+	// Flush the spans of the last connect and disconnect.
+	if shutdownTracing != nil {
+		_ = shutdownTracing(context.Background())
+	}
+}
+
+// signInDevice authorizes with GitHub using the device flow
+func signInDevice() error {
+	token, login, err := nerf.AuthDevice(context.Background(), func(code *nerf.DeviceCode) {
+		fmt.Printf("Visit %s and enter the code:\n\t%s\n", code.VerificationURI, code.UserCode)
+	})
+	if err != nil {
+		return err
+	}
+	nerf.Cfg.Token = token
+	nerf.Cfg.Login = login
+
+	fmt.Printf("Signed in as %s\n", nerf.Cfg.Login)
+
+	return nerf.SaveToken()
 }
 
 func onReady() {
@@ -46,83 +116,155 @@ func onReady() {
 	mRemoteIP = systray.AddMenuItem("Remote IP:", "Remote IP address")
 	mRemoteIP.Disable()
 	mRemoteIP.Hide()
+	mEvent = systray.AddMenuItem("", "Last message from the server")
+	mEvent.Disable()
+	mEvent.Hide()
 	systray.AddSeparator()
+	mEndpoints = systray.AddMenuItem("Endpoints", "Select the endpoint to connect to")
+	mAutomatic = mEndpoints.AddSubMenuItemCheckbox("Automatic", "Select the fastest endpoint", true)
 	mConnect = systray.AddMenuItem("Connect", "Connect to Hostinger Network")
 	mDisconnect = systray.AddMenuItem("Disconnect", "Disconnect from Hostinger Network")
+	mSignOut = systray.AddMenuItem("Sign out", "Forget the GitHub authorization")
 	mQuitOrig = systray.AddMenuItem("Quit", "Quit")
 
-	nerf.Cfg = nerf.NewConfig()
-
-	logger, _ := zap.Config{
-		Encoding:    "json",
-		Level:       zap.NewAtomicLevelAt(zapcore.DebugLevel),
-		OutputPaths: []string{"stdout"},
-		EncoderConfig: zapcore.EncoderConfig{
-			TimeKey:    "timestamp",
-			EncodeTime: zapcore.ISO8601TimeEncoder,
-			MessageKey: "message",
-		},
-	}.Build()
-
-	nerf.Cfg.Logger = logger
-
 	mDisconnect.Hide()
+	if !nerf.LoadToken() {
+		mSignOut.Hide()
+	}
+
+	go watchStatus()
+	go refreshEndpoints()
+	go func() {
+		for range mAutomatic.ClickedCh {
+			selectEndpoint("")
+		}
+	}()
 
-	go func(cfg *nerf.Config) {
+	go func() {
 		for {
 			select {
 			case <-mConnect.ClickedCh:
-				guiConnecting()
-				connect()
-				if cfg.Connected {
-					guiConnected()
-				} else {
-					guiDisconnected()
-				}
+				guiMutex.Lock()
+				guiConnecting("Connecting")
+				guiMutex.Unlock()
+				go connect()
 			case <-mDisconnect.ClickedCh:
-				disconnect()
-				if !cfg.Connected {
-					guiDisconnected()
+				guiMutex.Lock()
+				state := guiState
+				guiMutex.Unlock()
+				if state == nerf.Status_CONNECTED {
+					disconnect()
+				} else {
+					cancelConnect()
 				}
+			case <-mSignOut.ClickedCh:
+				go signOut()
 			case <-mQuitOrig.ClickedCh:
+				cancelConnect()
 				disconnect()
 				systray.Quit()
 				return
 			}
 		}
-	}(&nerf.Cfg)
+	}()
+
+	// Interrupting the GUI quits it the same way as the Quit menu item.
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+		cancelConnect()
+		disconnect()
+		systray.Quit()
+	}()
 }
 
-func ping() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// watchStatus updates the menu on every change of the connection status
+func watchStatus() {
+	for {
+		conn, err := grpcConnection()
+		if err == nil {
+			var stream nerf.Api_WatchStatusClient
+			stream, err = nerf.NewApiClient(conn).WatchStatus(
+				context.Background(),
+				&nerf.Notify{Login: nerf.Cfg.Login},
+			)
+			for err == nil {
+				var current *nerf.Status
+				if current, err = stream.Recv(); err == nil {
+					guiStatus(current)
+				}
+			}
+			conn.Close()
+		}
 
-	conn, _ := grpcConnection()
-	defer conn.Close()
+		nerf.Cfg.Logger.Debug("can't watch status", zap.Error(err))
+		guiStatus(&nerf.Status{
+			State: nerf.Status_DISCONNECTED,
+			Error: "nerf-api is not running",
+		})
+		time.Sleep(1 * time.Second)
+	}
+}
 
-	client := nerf.NewApiClient(conn)
-	data := time.Now().UnixNano()
-	request := &nerf.PingRequest{Data: data, Login: nerf.Cfg.Login}
-	_, err := client.Ping(ctx, request)
-	if err != nil {
+// stateTitles are user-readable names of connection states
+var stateTitles = map[nerf.Status_State]string{
+	nerf.Status_DISCONNECTED:    "Not connected",
+	nerf.Status_DISCOVERING:     "Discovering",
+	nerf.Status_AUTHENTICATING:  "Authenticating",
+	nerf.Status_CONFIGURING:     "Configuring",
+	nerf.Status_STARTING_NEBULA: "Starting Nebula",
+	nerf.Status_CONNECTED:       "Connected",
+	nerf.Status_RECONNECTING:    "Reconnecting",
+	nerf.Status_DISCONNECTING:   "Disconnecting",
+}
+
+// guiStatus updates the menu according to the connection status
+func guiStatus(current *nerf.Status) {
+	guiMutex.Lock()
+	defer guiMutex.Unlock()
+
+	guiState = current.State
+	switch current.State {
+	case nerf.Status_CONNECTED:
+		if !nerf.Cfg.Connected {
+			nerf.Cfg.Connected = true
+			connectionTime = time.Unix(current.Since, 0)
+			guiConnected()
+		}
+		mRemoteIP.SetTitle("Remote IP: " + current.RemoteIP)
+	case nerf.Status_DISCONNECTED:
+		nerf.Cfg.Connected = false
+		guiDisconnected()
+		if current.Error != "" {
+			mEvent.SetTitle(current.Error)
+			mEvent.Show()
+		}
+	default:
 		nerf.Cfg.Connected = false
+		guiConnecting(stateTitles[current.State])
 	}
 }
 
+// guiConnected shows the connection duration, must be called with guiMutex locked
 func guiConnected() {
 	systray.SetIcon(icons.Connected)
 	mStatus.SetTitle("Status: Connected")
-	mRemoteIP.SetTitle("Remote IP: " + nerf.Cfg.CurrentEndpoint.RemoteIP)
 	mDisconnect.SetTitle("Disconnect")
+	mConnect.Hide()
 	mRemoteIP.Show()
 	mDisconnect.Show()
-	connectionTicker = time.NewTicker(1 * time.Second)
+
+	if connectionTicker != nil {
+		connectionTicker.Stop()
+	}
+	ticker := time.NewTicker(1 * time.Second)
+	connectionTicker = ticker
 	go func() {
-		for {
-			<-connectionTicker.C
-			ping()
-			if !nerf.Cfg.Connected {
-				disconnect()
+		for range ticker.C {
+			guiMutex.Lock()
+			if ticker != connectionTicker || !nerf.Cfg.Connected {
+				guiMutex.Unlock()
 				return
 			}
 			connectionDuration := int(time.Since(connectionTime).Seconds())
@@ -134,11 +276,18 @@ func guiConnected() {
 					connectionDuration%60,
 				) + ")",
 			)
+			guiMutex.Unlock()
 		}
 	}()
 }
 
+// guiDisconnected must be called with guiMutex locked
 func guiDisconnected() {
+	if connectionTicker != nil {
+		connectionTicker.Stop()
+		connectionTicker = nil
+	}
+
 	systray.SetIcon(icons.Disconnected)
 	mStatus.SetTitle("Status: Not connected")
 	mRemoteIP.Hide()
@@ -147,10 +296,19 @@ func guiDisconnected() {
 	mDisconnect.Hide()
 }
 
-func guiConnecting() {
+// guiConnecting must be called with guiMutex locked
+func guiConnecting(title string) {
+	if connectionTicker != nil {
+		connectionTicker.Stop()
+		connectionTicker = nil
+	}
+
 	systray.SetIcon(icons.Connecting)
-	mStatus.SetTitle("Status: Connecting")
+	mStatus.SetTitle("Status: " + title)
+	mRemoteIP.Hide()
 	mConnect.Hide()
+	mDisconnect.SetTitle("Cancel")
+	mDisconnect.Show()
 }
 
 func connect() {
@@ -158,9 +316,153 @@ func connect() {
 		return
 	}
 
-	nerf.Auth()
+	ctx, span := nerf.StartSpan(nerf.WithRequestID(context.Background()), "connect")
+	defer span.End()
+
+	logger := nerf.Cfg.Logger.With(nerf.TraceFields(ctx)...)
+
+	// Cancel menu item aborts signing in as well.
+	ctx, cancel := context.WithCancel(ctx)
+	guiMutex.Lock()
+	cancelSignIn = cancel
+	guiMutex.Unlock()
+	defer func() {
+		guiMutex.Lock()
+		cancelSignIn = nil
+		guiMutex.Unlock()
+		cancel()
+	}()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	authCtx, authSpan := nerf.StartSpan(ctx, "authorize")
+	reused, err := signIn(authCtx)
+	authSpan.End()
+	if err != nil {
+		signInFailed(ctx, err)
+		return
+	}
+	span.SetAttributes(nerf.LoginAttribute(nerf.Cfg.Login))
+
+	conn, err := grpcConnection()
+	if err != nil {
+		guiMutex.Lock()
+		guiDisconnected()
+		guiMutex.Unlock()
+		return
+	}
+	defer conn.Close()
+
+	client := nerf.NewApiClient(conn)
+	err = requestConnect(ctx, client)
+
+	// The stored token is reused until GitHub rejects it.
+	if reused && nerf.ErrorReason(err) == nerf.ReasonUnauthenticated {
+		logger.Info("stored token is rejected, signing in again")
+		if err := nerf.DeleteToken(); err != nil {
+			logger.Error("can't delete stored token", zap.Error(err))
+		}
+		authCtx, authSpan = nerf.StartSpan(ctx, "authorize")
+		_, err = signIn(authCtx)
+		authSpan.End()
+		if err != nil {
+			signInFailed(ctx, err)
+			return
+		}
+		err = requestConnect(ctx, client)
+	}
+
+	if err != nil {
+		logger.Error("can't connect",
+			zap.String("Reason", nerf.ErrorReason(err)),
+			zap.Error(err))
+		guiFailed(status.Convert(err).Message())
+		return
+	}
+
+	// The menu is updated by watchStatus.
+	eventsCtx, cancel := context.WithCancel(context.Background())
+	stopWatchingEvents = cancel
+	go watchEvents(eventsCtx)
+	go refreshEndpoints()
+}
+
+// requestConnect asks nerf-api to connect
+func requestConnect(ctx context.Context, client nerf.ApiClient) error {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	_, err := client.Connect(ctx, &nerf.Request{
+		Login:    nerf.Cfg.Login,
+		Token:    nerf.Cfg.Token,
+		Endpoint: nerf.Cfg.PreferredEndpoint,
+	})
+
+	return err
+}
+
+// signIn restores the stored OAuth token, or authorizes in the browser
+func signIn(ctx context.Context) (bool, error) {
+	if nerf.LoadToken() {
+		return true, nil
+	}
+
+	token, login, err := nerf.Auth(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	nerf.Cfg.Token = token
+	nerf.Cfg.Login = login
+	if err := nerf.SaveToken(); err != nil {
+		nerf.Cfg.Logger.Error("can't store token", zap.Error(err))
+	}
+
+	guiMutex.Lock()
+	mSignOut.Show()
+	guiMutex.Unlock()
+
+	return false, nil
+}
+
+// signInFailed shows why signing in failed, unless it was canceled
+func signInFailed(ctx context.Context, err error) {
+	if errors.Is(err, context.Canceled) {
+		guiMutex.Lock()
+		guiDisconnected()
+		guiMutex.Unlock()
+		return
+	}
+
+	nerf.Cfg.Logger.With(nerf.TraceFields(ctx)...).Error("can't sign in", zap.Error(err))
+	guiFailed(fmt.Sprintf("Can't sign in: %s", err))
+}
+
+// guiFailed shows the menu for disconnected state with the error message
+func guiFailed(message string) {
+	guiMutex.Lock()
+	defer guiMutex.Unlock()
+
+	guiDisconnected()
+	mEvent.SetTitle(message)
+	mEvent.Show()
+}
+
+// signOut disconnects and deletes the stored OAuth token
+func signOut() {
+	cancelConnect()
+	disconnect()
+
+	if err := nerf.DeleteToken(); err != nil {
+		nerf.Cfg.Logger.Error("can't delete stored token", zap.Error(err))
+	}
+
+	guiMutex.Lock()
+	mSignOut.Hide()
+	guiMutex.Unlock()
+}
+
+// refreshEndpoints lists endpoints in the Endpoints submenu
+func refreshEndpoints() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	conn, err := grpcConnection()
@@ -169,41 +471,163 @@ func connect() {
 	}
 	defer conn.Close()
 
+	response, err := nerf.NewApiClient(conn).GetEndpoints(ctx, &nerf.Notify{Login: nerf.Cfg.Login})
+	if err != nil {
+		nerf.Cfg.Logger.Error("can't get endpoints", zap.Error(err))
+		return
+	}
+
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+
+	// Menu items can't be removed, thus reuse them and hide the rest.
+	for i, e := range response.Endpoints {
+		title := e.Description + " (unreachable)"
+		if e.Reachable {
+			title = fmt.Sprintf("%s (%d ms)", e.Description, e.Latency)
+		}
+
+		if i == len(mEndpointItems) {
+			item := mEndpoints.AddSubMenuItemCheckbox(title, e.RemoteHost, false)
+			mEndpointItems = append(mEndpointItems, item)
+			endpointHosts = append(endpointHosts, e.RemoteHost)
+			go func(i int) {
+				for range item.ClickedCh {
+					endpointsMutex.Lock()
+					host := endpointHosts[i]
+					endpointsMutex.Unlock()
+					selectEndpoint(host)
+				}
+			}(i)
+		}
+
+		mEndpointItems[i].SetTitle(title)
+		mEndpointItems[i].SetTooltip(e.RemoteHost)
+		mEndpointItems[i].Show()
+		endpointHosts[i] = e.RemoteHost
+	}
+
+	for i := len(response.Endpoints); i < len(mEndpointItems); i++ {
+		mEndpointItems[i].Hide()
+		endpointHosts[i] = ""
+	}
+
+	checkSelectedEndpoint()
+}
+
+// checkSelectedEndpoint marks the preferred endpoint, must be called with endpointsMutex locked
+func checkSelectedEndpoint() {
+	if nerf.Cfg.PreferredEndpoint == "" {
+		mAutomatic.Check()
+	} else {
+		mAutomatic.Uncheck()
+	}
+
+	for i, item := range mEndpointItems {
+		if endpointHosts[i] != "" && endpointHosts[i] == nerf.Cfg.PreferredEndpoint {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+}
+
+// selectEndpoint sets the endpoint to connect to, empty host selects the fastest one
+func selectEndpoint(host string) {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+
+	nerf.Cfg.PreferredEndpoint = host
+	checkSelectedEndpoint()
+
+	if nerf.Cfg.Connected {
+		mEvent.SetTitle("The selected endpoint is used after reconnecting")
+		mEvent.Show()
+	}
+}
+
+// watchEvents shows messages from the server forwarded by nerf-api
+func watchEvents(ctx context.Context) {
+	conn, err := grpcConnection()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
 	client := nerf.NewApiClient(conn)
-	request := &nerf.Request{Login: nerf.Cfg.Login, Token: nerf.Cfg.Token}
-	response, err := client.Connect(ctx, request)
+	stream, err := client.WatchEvents(ctx, &nerf.Notify{Login: nerf.Cfg.Login})
 	if err != nil {
+		nerf.Cfg.Logger.Error("can't watch events", zap.Error(err))
 		return
 	}
 
-	connectionTime = time.Now()
-	nerf.Cfg.Connected = true
-	nerf.Cfg.CurrentEndpoint.RemoteIP = response.RemoteIP
-	nerf.Cfg.ClientIP = response.ClientIP
-	guiConnected()
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return
+		}
+
+		nerf.Cfg.Logger.Info("received event",
+			zap.String("Type", event.Type.String()),
+			zap.String("Message", event.Message))
+
+		guiMutex.Lock()
+		mEvent.SetTitle(event.Message)
+		mEvent.Show()
+		guiMutex.Unlock()
+
+		// The connection status itself is updated by watchStatus.
+		switch event.Type {
+		case nerf.Event_ENDPOINT_SWITCHED:
+			go refreshEndpoints()
+		case nerf.Event_FORCED_DISCONNECT:
+			return
+		}
+	}
 }
 
-func disconnect() {
-	connectionTicker.Stop()
+// cancelConnect aborts the connect in progress
+func cancelConnect() {
+	guiMutex.Lock()
+	if cancelSignIn != nil {
+		cancelSignIn()
+	}
+	guiMutex.Unlock()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	conn, err := grpcConnection()
 	if err != nil {
-		guiDisconnected()
 		return
 	}
 	defer conn.Close()
 
 	client := nerf.NewApiClient(conn)
-	request := &nerf.Notify{Login: nerf.Cfg.Login}
-	_, err = client.Disconnect(ctx, request)
+	if _, err = client.CancelConnect(ctx, &nerf.Notify{Login: nerf.Cfg.Login}); err != nil {
+		nerf.Cfg.Logger.Debug("can't cancel connect", zap.Error(err))
+	}
+}
+
+func disconnect() {
+	if stopWatchingEvents != nil {
+		stopWatchingEvents()
+		stopWatchingEvents = nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := grpcConnection()
 	if err != nil {
-		guiDisconnected()
 		return
 	}
+	defer conn.Close()
 
-	guiDisconnected()
-	nerf.Cfg.Connected = false
+	// The menu is updated by watchStatus.
+	client := nerf.NewApiClient(conn)
+	request := &nerf.Notify{Login: nerf.Cfg.Login}
+	if _, err = client.Disconnect(ctx, request); err != nil {
+		nerf.Cfg.Logger.Error("can't disconnect", zap.Error(err))
+	}
 }
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ton31337/nerf"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var UnixSockAddr = "unix:" + nerf.ApiSocket()
+
+var socketAddr string
+var jsonOutput bool
+
+// commands maps subcommands to their handlers and descriptions
+var commands = []struct {
+	name        string
+	description string
+	run         func(ctx context.Context, args []string) error
+}{
+	{"login", "Sign in with GitHub and store the token", login},
+	{"logout", "Delete the stored token", logout},
+	{"connect", "Connect to the fastest or the given endpoint", connect},
+	{"disconnect", "Disconnect and wait until the tunnel is down", disconnect},
+	{"status", "Show the connection status", showStatus},
+	{"endpoints", "List endpoints with their latency", endpoints},
+	{"logs", "Follow status changes and events until interrupted", logs},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] <command> [command flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-12s%s\n", c.name, c.description)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.StringVar(&socketAddr, "socket", UnixSockAddr, "Set the address of nerf-api gRPC socket")
+	flag.BoolVar(&jsonOutput, "json", false, "Print output in JSON, one object per line")
+	logLevel := flag.String(
+		"log-level",
+		"warn",
+		"Set the logging level - values are 'debug', 'info', 'warn', and 'error'",
+	)
+	flag.Usage = usage
+	flag.Parse()
+
+	nerf.Cfg = nerf.NewConfig()
+
+	logger, _ := zap.Config{
+		Encoding:    "json",
+		Level:       zap.NewAtomicLevelAt(nerf.StringToLogLevel(*logLevel)),
+		OutputPaths: []string{"stderr"},
+		EncoderConfig: zapcore.EncoderConfig{
+			TimeKey:    "timestamp",
+			EncodeTime: zapcore.ISO8601TimeEncoder,
+			MessageKey: "message",
+		},
+	}.Build()
+
+	nerf.Cfg.Logger = logger
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	// Interrupting cancels the command, e.g. connect in progress is aborted.
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	for _, c := range commands {
+		if c.name != flag.Arg(0) {
+			continue
+		}
+		if err := c.run(ctx, flag.Args()[1:]); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", flag.Arg(0))
+	usage()
+	os.Exit(2)
+}
+
+// printError prints the message of the error, with the reason if nerf-api reported one
+func printError(err error) {
+	message := status.Convert(err).Message()
+	reason := nerf.ErrorReason(err)
+
+	if jsonOutput {
+		output, _ := json.Marshal(map[string]string{"error": message, "reason": reason})
+		fmt.Fprintln(os.Stderr, string(output))
+		return
+	}
+
+	if reason != "" {
+		fmt.Fprintf(os.Stderr, "Error: %s (%s)\n", message, reason)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %s\n", message)
+}
+
+// printJSON prints the message in JSON on a single line, including zero values
+func printJSON(m proto.Message) {
+	output, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(m)
+	if err != nil {
+		printError(err)
+		return
+	}
+	fmt.Println(string(output))
+}
+
+func apiClient() (nerf.ApiClient, *grpc.ClientConn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, socketAddr, nerf.GRPCDialOptions()...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't connect to nerf-api at %s: %v", socketAddr, err)
+	}
+
+	return nerf.NewApiClient(conn), conn, nil
+}
+
+func login(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	device := fs.Bool("device", false, "Sign in by entering a code on another device, instead of opening the browser")
+	_ = fs.Parse(args)
+
+	var token, user string
+	var err error
+	if *device {
+		token, user, err = nerf.AuthDevice(ctx, func(code *nerf.DeviceCode) {
+			fmt.Fprintf(os.Stderr, "Visit %s and enter the code:\n\t%s\n", code.VerificationURI, code.UserCode)
+		})
+	} else {
+		token, user, err = nerf.Auth(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	nerf.Cfg.Token = token
+	nerf.Cfg.Login = user
+
+	if err := nerf.SaveToken(); err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		output, _ := json.Marshal(map[string]string{"login": nerf.Cfg.Login})
+		fmt.Println(string(output))
+		return nil
+	}
+	fmt.Printf("Signed in as %s\n", nerf.Cfg.Login)
+
+	return nil
+}
+
+func logout(ctx context.Context, args []string) error {
+	return nerf.DeleteToken()
+}
+
+func connect(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("connect", flag.ExitOnError)
+	endpoint := fs.String("endpoint", "", "Connect to this endpoint (host name or IP address)")
+	region := fs.String("region", "", "Connect to an endpoint whose description contains the region")
+	timeout := fs.Duration("timeout", 60*time.Second, "Give up connecting after the timeout")
+	_ = fs.Parse(args)
+
+	if !nerf.LoadToken() {
+		return errors.New("not signed in, run 'nerfctl login' first")
+	}
+
+	client, conn, err := apiClient()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	response, err := client.Connect(ctx, &nerf.Request{
+		Login:    nerf.Cfg.Login,
+		Token:    nerf.Cfg.Token,
+		Endpoint: *endpoint,
+		Region:   *region,
+	})
+	if nerf.ErrorReason(err) == nerf.ReasonUnauthenticated {
+		return fmt.Errorf("%s, run 'nerfctl login'", status.Convert(err).Message())
+	}
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		printJSON(response)
+		return nil
+	}
+	fmt.Printf("Connected to %s, client IP %s\n", response.RemoteIP, response.ClientIP)
+
+	return nil
+}
+
+func disconnect(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("disconnect", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 30*time.Second, "Give up waiting for the tunnel to go down after the timeout")
+	_ = fs.Parse(args)
+
+	_ = nerf.LoadToken()
+
+	client, conn, err := apiClient()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	// Watch before disconnecting, thus reaching Disconnected state is not missed.
+	stream, err := client.WatchStatus(ctx, &nerf.Notify{Login: nerf.Cfg.Login})
+	if err != nil {
+		return err
+	}
+	current, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	if current.State != nerf.Status_DISCONNECTED {
+		if _, err = client.Disconnect(ctx, &nerf.Notify{Login: nerf.Cfg.Login}); err != nil {
+			return err
+		}
+		for current.State != nerf.Status_DISCONNECTED {
+			if current, err = stream.Recv(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if jsonOutput {
+		printJSON(current)
+		return nil
+	}
+	fmt.Println("Disconnected")
+
+	return nil
+}
+
+func showStatus(ctx context.Context, args []string) error {
+	client, conn, err := apiClient()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	current, err := client.GetStatus(ctx, &nerf.Notify{Login: nerf.Cfg.Login})
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		printJSON(current)
+		return nil
+	}
+
+	return writeStatus(os.Stdout, current, time.Now())
+}
+
+// writeStatus prints the status as a table, with the time in the state counted up to now
+func writeStatus(out io.Writer, current *nerf.Status, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	since := now.Sub(time.Unix(current.Since, 0)).Round(time.Second)
+	fmt.Fprintf(w, "State:\t%s (for %s)\n", current.State, since)
+	if current.RemoteHost != "" {
+		fmt.Fprintf(w, "Endpoint:\t%s (%s, %s)\n", current.Description, current.RemoteHost, current.RemoteIP)
+	}
+	if current.ClientIP != "" {
+		fmt.Fprintf(w, "Client IP:\t%s\n", current.ClientIP)
+	}
+	if len(current.Teams) > 0 {
+		fmt.Fprintf(w, "Teams:\t%s\n", strings.Join(current.Teams, ", "))
+	}
+	if current.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s (%s)\n", current.Error, current.ErrorReason)
+	}
+
+	return w.Flush()
+}
+
+func endpoints(ctx context.Context, args []string) error {
+	client, conn, err := apiClient()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	response, err := client.GetEndpoints(ctx, &nerf.Notify{Login: nerf.Cfg.Login})
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		printJSON(response)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tDESCRIPTION\tIP\tPRIORITY\tWEIGHT\tLATENCY\tJITTER\tCURRENT")
+	for _, e := range response.Endpoints {
+		latency, jitter := "unreachable", "-"
+		if e.Reachable {
+			latency = fmt.Sprintf("%d ms", e.Latency)
+			jitter = fmt.Sprintf("%d ms", e.Jitter)
+		}
+		current := ""
+		if e.Current {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n",
+			e.RemoteHost, e.Description, e.RemoteIP, e.Priority, e.Weight, latency, jitter, current)
+	}
+
+	return w.Flush()
+}
+
+func logs(ctx context.Context, args []string) error {
+	client, conn, err := apiClient()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	statuses, err := client.WatchStatus(ctx, &nerf.Notify{Login: nerf.Cfg.Login})
+	if err != nil {
+		return err
+	}
+	events, err := client.WatchEvents(ctx, &nerf.Notify{Login: nerf.Cfg.Login})
+	if err != nil {
+		return err
+	}
+
+	lines := make(chan string)
+	errs := make(chan error, 2)
+
+	go func() {
+		for {
+			s, err := statuses.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			lines <- formatStatus(s, jsonOutput)
+		}
+	}()
+	go func() {
+		for {
+			e, err := events.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			lines <- formatEvent(e, jsonOutput)
+		}
+	}()
+
+	for {
+		select {
+		case line := <-lines:
+			fmt.Println(line)
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// formatStatus formats the status change as a line of logs
+func formatStatus(s *nerf.Status, asJSON bool) string {
+	if asJSON {
+		output, _ := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(s)
+		return fmt.Sprintf(`{"status":%s}`, output)
+	}
+
+	line := fmt.Sprintf("%s status %s", time.Unix(s.Since, 0).Format(time.RFC3339), s.State)
+	if s.RemoteHost != "" {
+		line += fmt.Sprintf(" endpoint=%s", s.RemoteHost)
+	}
+	if s.Error != "" {
+		line += fmt.Sprintf(" error=%q reason=%s", s.Error, s.ErrorReason)
+	}
+
+	return line
+}
+
+// formatEvent formats the event as a line of logs
+func formatEvent(e *nerf.Event, asJSON bool) string {
+	if asJSON {
+		output, _ := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(e)
+		return fmt.Sprintf(`{"event":%s}`, output)
+	}
+
+	return fmt.Sprintf("%s event %s %q", time.Unix(e.Timestamp, 0).Format(time.RFC3339), e.Type, e.Message)
+}
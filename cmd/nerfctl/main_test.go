@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ton31337/nerf"
+)
+
+func TestFormatStatus(t *testing.T) {
+	since := time.Date(2021, 6, 1, 12, 0, 0, 0, time.Local)
+	timestamp := since.Format(time.RFC3339)
+
+	tests := []struct {
+		name   string
+		status *nerf.Status
+		want   string
+	}{
+		{
+			name:   "disconnected",
+			status: &nerf.Status{State: nerf.Status_DISCONNECTED, Since: since.Unix()},
+			want:   timestamp + " status DISCONNECTED",
+		},
+		{
+			name: "connected",
+			status: &nerf.Status{
+				State:      nerf.Status_CONNECTED,
+				Since:      since.Unix(),
+				RemoteHost: "vpn-lt.example.com",
+			},
+			want: timestamp + " status CONNECTED endpoint=vpn-lt.example.com",
+		},
+		{
+			name: "failed",
+			status: &nerf.Status{
+				State:       nerf.Status_DISCONNECTED,
+				Since:       since.Unix(),
+				Error:       "No reachable endpoints found",
+				ErrorReason: nerf.ReasonNoEndpoints,
+			},
+			want: timestamp + ` status DISCONNECTED error="No reachable endpoints found" reason=` + nerf.ReasonNoEndpoints,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatStatus(tt.status, false); got != tt.want {
+				t.Errorf("formatStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatStatusJSON(t *testing.T) {
+	line := formatStatus(&nerf.Status{State: nerf.Status_CONNECTED, ClientIP: "10.0.0.2"}, true)
+
+	var got struct {
+		Status map[string]interface{} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(line), &got); err != nil {
+		t.Fatalf("formatStatus() = %s, not JSON: %v", line, err)
+	}
+	if got.Status["state"] != "CONNECTED" || got.Status["clientIP"] != "10.0.0.2" {
+		t.Errorf("formatStatus() = %s", line)
+	}
+	// Unpopulated fields are emitted, thus scripts can rely on them.
+	if _, ok := got.Status["error"]; !ok {
+		t.Errorf("formatStatus() = %s, want the error field", line)
+	}
+}
+
+func TestFormatEvent(t *testing.T) {
+	timestamp := time.Date(2021, 6, 1, 12, 0, 0, 0, time.Local)
+	event := &nerf.Event{
+		Type:      nerf.Event_ENDPOINT_SWITCHED,
+		Message:   `Switched to "Vilnius" (faster endpoint)`,
+		Timestamp: timestamp.Unix(),
+	}
+
+	want := timestamp.Format(time.RFC3339) + ` event ENDPOINT_SWITCHED "Switched to \"Vilnius\" (faster endpoint)"`
+	if got := formatEvent(event, false); got != want {
+		t.Errorf("formatEvent() = %q, want %q", got, want)
+	}
+
+	line := formatEvent(event, true)
+	var got struct {
+		Event map[string]interface{} `json:"event"`
+	}
+	if err := json.Unmarshal([]byte(line), &got); err != nil {
+		t.Fatalf("formatEvent() = %s, not JSON: %v", line, err)
+	}
+	if got.Event["type"] != "ENDPOINT_SWITCHED" || got.Event["message"] != event.Message {
+		t.Errorf("formatEvent() = %s", line)
+	}
+}
+
+func TestWriteStatus(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		status *nerf.Status
+		want   []string
+	}{
+		{
+			name:   "disconnected",
+			status: &nerf.Status{State: nerf.Status_DISCONNECTED, Since: now.Add(-90 * time.Second).Unix()},
+			want:   []string{"State:  DISCONNECTED (for 1m30s)"},
+		},
+		{
+			name: "connected",
+			status: &nerf.Status{
+				State:       nerf.Status_CONNECTED,
+				Since:       now.Unix(),
+				RemoteHost:  "vpn-lt.example.com",
+				Description: "Vilnius",
+				RemoteIP:    "192.0.2.1",
+				ClientIP:    "10.0.0.2",
+				Teams:       []string{"sre", "dev"},
+			},
+			want: []string{
+				"State:      CONNECTED (for 0s)",
+				"Endpoint:   Vilnius (vpn-lt.example.com, 192.0.2.1)",
+				"Client IP:  10.0.0.2",
+				"Teams:      sre, dev",
+			},
+		},
+		{
+			name: "failed",
+			status: &nerf.Status{
+				State:       nerf.Status_DISCONNECTED,
+				Since:       now.Unix(),
+				Error:       "Connecting was cancelled",
+				ErrorReason: nerf.ReasonCanceled,
+			},
+			want: []string{
+				"State:  DISCONNECTED (for 0s)",
+				"Error:  Connecting was cancelled (" + nerf.ReasonCanceled + ")",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writeStatus(&out, tt.status, time.Unix(now.Unix(), 0)); err != nil {
+				t.Fatal(err)
+			}
+
+			got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("writeStatus() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
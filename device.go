@@ -0,0 +1,170 @@
+package nerf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+// deviceIntervalUnit is the unit of intervals and expiration of device codes
+var deviceIntervalUnit = time.Second
+
+// githubAPIURL is the base URL of GitHub API requests authorized by the device flow
+var githubAPIURL = "https://api.github.com/"
+
+// DeviceCode is the response of the device authorization endpoint (RFC 8628)
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// deviceToken is the response of the token endpoint while polling
+type deviceToken struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	Interval         int    `json:"interval"`
+}
+
+// devicePost posts the form and decodes JSON response into v
+func devicePost(ctx context.Context, endpoint string, form url.Values, v interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("failed request to %s: %s", endpoint, resp.Status)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// githubLogin returns the login of the user authorized by the token
+func githubLogin(ctx context.Context, accessToken string) (string, error) {
+	oauthClient := oauth2.NewClient(ctx, &TokenSource{AccessToken: accessToken})
+	client := github.NewClient(oauthClient)
+	baseURL, err := url.Parse(githubAPIURL)
+	if err != nil {
+		return "", err
+	}
+	client.BaseURL = baseURL
+
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return "", err
+	}
+
+	return user.GetLogin(), nil
+}
+
+// AuthDevice handles OAuth authentication with the device authorization grant
+func AuthDevice(ctx context.Context, prompt func(code *DeviceCode)) (string, string, error) {
+	code := &DeviceCode{}
+	err := devicePost(ctx, Cfg.DeviceAuthURL, url.Values{
+		"client_id": {Cfg.OAuth.ClientID},
+		"scope":     {strings.Join(Cfg.OAuth.Scopes, " ")},
+	}, code)
+	if err != nil {
+		return "", "", err
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return "", "", fmt.Errorf("device authorization is not supported")
+	}
+
+	prompt(code)
+
+	interval := time.Duration(code.Interval) * deviceIntervalUnit
+	if interval <= 0 {
+		interval = 5 * deviceIntervalUnit
+	}
+	expiresIn := time.Duration(code.ExpiresIn) * deviceIntervalUnit
+	if expiresIn <= 0 {
+		expiresIn = 15 * 60 * deviceIntervalUnit
+	}
+	ctx, cancel := context.WithTimeout(ctx, expiresIn)
+	defer cancel()
+
+	// done tells whether polling was cancelled or the code expired
+	done := func() error {
+		if ctx.Err() == context.Canceled {
+			return ctx.Err()
+		}
+		return fmt.Errorf("device code expired")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", "", done()
+		case <-time.After(interval):
+		}
+
+		token := &deviceToken{}
+		err := devicePost(ctx, Cfg.OAuth.Endpoint.TokenURL, url.Values{
+			"client_id":   {Cfg.OAuth.ClientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, token)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", "", done()
+			}
+			return "", "", err
+		}
+
+		switch token.Error {
+		case "":
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * deviceIntervalUnit
+			if token.Interval > 0 {
+				interval = time.Duration(token.Interval) * deviceIntervalUnit
+			}
+			continue
+		case "access_denied":
+			return "", "", fmt.Errorf("authorization denied")
+		case "expired_token":
+			return "", "", fmt.Errorf("device code expired")
+		default:
+			return "", "", fmt.Errorf("device authorization failed: %s %s", token.Error, token.ErrorDescription)
+		}
+
+		if token.AccessToken == "" {
+			return "", "", fmt.Errorf("device authorization failed: no access token")
+		}
+
+		login, err := githubLogin(ctx, token.AccessToken)
+		if err != nil {
+			return "", "", err
+		}
+
+		return token.AccessToken, login, nil
+	}
+}
@@ -0,0 +1,177 @@
+package nerf
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// serveDeviceFlow stubs the device authorization, token and user endpoints.
+// The token endpoint answers with the responses in order, repeating the last one.
+// Times of polling the token endpoint are returned by the returned function.
+func serveDeviceFlow(t *testing.T, responses []deviceToken) func() []time.Time {
+	var mutex sync.Mutex
+	var polls []time.Time
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/device/code", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(DeviceCode{
+			DeviceCode:      "device-code",
+			UserCode:        "ABCD-1234",
+			VerificationURI: "https://github.com/login/device",
+			ExpiresIn:       100,
+			Interval:        1,
+		})
+	})
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("device_code") != "device-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "incorrect_device_code"}`))
+			return
+		}
+
+		mutex.Lock()
+		polls = append(polls, time.Now())
+		response := responses[len(responses)-1]
+		if len(polls) <= len(responses) {
+			response = responses[len(polls)-1]
+		}
+		mutex.Unlock()
+
+		if response.Error != "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	})
+	mux.HandleFunc("/api/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"login": "alice"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	oauth, deviceAuthURL, apiURL, unit := Cfg.OAuth, Cfg.DeviceAuthURL, githubAPIURL, deviceIntervalUnit
+	t.Cleanup(func() {
+		Cfg.OAuth, Cfg.DeviceAuthURL, githubAPIURL, deviceIntervalUnit = oauth, deviceAuthURL, apiURL, unit
+	})
+
+	Cfg.OAuth = &oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{TokenURL: server.URL + "/login/oauth/access_token"},
+	}
+	Cfg.DeviceAuthURL = server.URL + "/login/device/code"
+	githubAPIURL = server.URL + "/api/"
+	deviceIntervalUnit = 10 * time.Millisecond
+
+	return func() []time.Time {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]time.Time(nil), polls...)
+	}
+}
+
+func TestAuthDevice(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []deviceToken
+		wantErr   string
+		wantPolls int
+	}{
+		{
+			name:      "success",
+			responses: []deviceToken{{AccessToken: "secret"}},
+			wantPolls: 1,
+		},
+		{
+			name:      "authorization pending",
+			responses: []deviceToken{{Error: "authorization_pending"}, {Error: "authorization_pending"}, {AccessToken: "secret"}},
+			wantPolls: 3,
+		},
+		{
+			name:      "slow down",
+			responses: []deviceToken{{Error: "slow_down", Interval: 20}, {AccessToken: "secret"}},
+			wantPolls: 2,
+		},
+		{
+			name:      "expired token",
+			responses: []deviceToken{{Error: "authorization_pending"}, {Error: "expired_token"}},
+			wantErr:   "device code expired",
+			wantPolls: 2,
+		},
+		{
+			name:      "access denied",
+			responses: []deviceToken{{Error: "access_denied"}},
+			wantErr:   "authorization denied",
+			wantPolls: 1,
+		},
+		{
+			name:      "code expires while pending",
+			responses: []deviceToken{{Error: "authorization_pending"}},
+			wantErr:   "device code expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polls := serveDeviceFlow(t, tt.responses)
+
+			var prompted *DeviceCode
+			token, login, err := AuthDevice(context.Background(), func(code *DeviceCode) {
+				prompted = code
+			})
+
+			if prompted == nil || prompted.UserCode != "ABCD-1234" {
+				t.Errorf("prompted with %+v", prompted)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("AuthDevice() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil || token != "secret" || login != "alice" {
+				t.Errorf("AuthDevice() = %q, %q, %v, want secret, alice", token, login, err)
+			}
+			if n := len(polls()); tt.wantPolls > 0 && n != tt.wantPolls {
+				t.Errorf("token endpoint polled %d times, want %d", n, tt.wantPolls)
+			}
+		})
+	}
+}
+
+func TestAuthDeviceSlowDown(t *testing.T) {
+	times := serveDeviceFlow(t, []deviceToken{{Error: "slow_down"}, {AccessToken: "secret"}})
+
+	if _, _, err := AuthDevice(context.Background(), func(*DeviceCode) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	// slow_down without an interval adds 5 units to the interval of 1 unit.
+	polls := times()
+	if len(polls) != 2 {
+		t.Fatalf("token endpoint polled %d times, want 2", len(polls))
+	}
+	if gap := polls[1].Sub(polls[0]); gap < 6*deviceIntervalUnit {
+		t.Errorf("polled again after %s, want at least %s", gap, 6*deviceIntervalUnit)
+	}
+}
+
+func TestAuthDeviceCancel(t *testing.T) {
+	serveDeviceFlow(t, []deviceToken{{Error: "authorization_pending"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if _, _, err := AuthDevice(ctx, func(*DeviceCode) {}); err != context.Canceled {
+		t.Errorf("AuthDevice() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,432 @@
+package nerf
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// DNSConfigurator configures name servers of the system while connected
+type DNSConfigurator interface {
+	// Name of the DNS stack, e.g. systemd-resolved
+	Name() string
+	// Set points the link to the name servers and the search domain.
+	// The current configuration is saved to revert to. If save is set
+	// or the link differs from the saved one, the previous changes are
+	// reverted first, thus only one link is ever pointed to Nebula.
+	Set(link *net.Interface, nameServers []string, domain string, save bool) error
+	// Revert restores the configuration saved by Set
+	Revert() error
+}
+
+const (
+	resolvConf = "/etc/resolv.conf"
+	// resolvconfRecord is ordered before the records of physical
+	// interfaces by the default interface-order of resolvconf
+	resolvconfRecord = "tun.nerf"
+)
+
+// dnsConfigurator is the DNS stack name servers were set with, used to revert them
+var dnsConfigurator DNSConfigurator
+
+// NewDNSConfigurator returns the configurator of the backend, detected if auto
+func NewDNSConfigurator(backend string) (DNSConfigurator, error) {
+	switch backend {
+	case "", "auto":
+		return detectDNSConfigurator(), nil
+	case "systemd-resolved":
+		return &resolvedDNS{}, nil
+	case "networkmanager":
+		return &networkManagerDNS{}, nil
+	case "resolvconf":
+		return &resolvconfDNS{}, nil
+	case "resolv.conf":
+		return newResolvConfDNS(), nil
+	}
+
+	return nil, fmt.Errorf("unknown DNS backend: %s", backend)
+}
+
+// detectDNSConfigurator picks the DNS stack managing /etc/resolv.conf
+func detectDNSConfigurator() DNSConfigurator {
+	if dbusNameHasOwner("org.freedesktop.resolve1") && resolvConfManagedByResolved() {
+		return &resolvedDNS{}
+	}
+
+	if dbusNameHasOwner("org.freedesktop.NetworkManager") {
+		return &networkManagerDNS{}
+	}
+
+	if _, err := exec.LookPath("resolvconf"); err == nil {
+		return &resolvconfDNS{}
+	}
+
+	return newResolvConfDNS()
+}
+
+// dbusNameHasOwner checks if the service is running on the system bus
+func dbusNameHasOwner(name string) bool {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return false
+	}
+
+	var hasOwner bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, name).Store(&hasOwner); err != nil {
+		return false
+	}
+
+	return hasOwner
+}
+
+// resolvConfManagedByResolved checks if /etc/resolv.conf points to systemd-resolved
+func resolvConfManagedByResolved() bool {
+	if target, err := os.Readlink(resolvConf); err == nil && strings.Contains(target, "/run/systemd/resolve/") {
+		return true
+	}
+
+	data, err := ioutil.ReadFile(resolvConf)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Contains(data, []byte("nameserver 127.0.0.53"))
+}
+
+// resolvedDNS configures systemd-resolved over D-Bus
+type resolvedDNS struct {
+	link    *net.Interface
+	dns     []resolvedAddress
+	domains []resolvedDomain
+}
+
+type resolvedAddress struct {
+	Family  int32
+	Address []byte
+}
+
+type resolvedDomain struct {
+	Domain      string
+	RoutingOnly bool
+}
+
+func (r *resolvedDNS) Name() string {
+	return "systemd-resolved"
+}
+
+func (r *resolvedDNS) manager() (dbus.BusObject, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.Object("org.freedesktop.resolve1", "/org/freedesktop/resolve1"), nil
+}
+
+// save reads the current name servers and domains of the link
+func (r *resolvedDNS) save(manager dbus.BusObject, link *net.Interface) error {
+	var linkPath dbus.ObjectPath
+	if err := manager.Call("org.freedesktop.resolve1.Manager.GetLink", 0, int32(link.Index)).Store(&linkPath); err != nil {
+		return err
+	}
+
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return err
+	}
+	linkObject := conn.Object("org.freedesktop.resolve1", linkPath)
+
+	dns, err := linkObject.GetProperty("org.freedesktop.resolve1.Link.DNS")
+	if err != nil {
+		return err
+	}
+	domains, err := linkObject.GetProperty("org.freedesktop.resolve1.Link.Domains")
+	if err != nil {
+		return err
+	}
+
+	r.dns = nil
+	r.domains = nil
+	if err := dns.Store(&r.dns); err != nil {
+		return err
+	}
+	if err := domains.Store(&r.domains); err != nil {
+		return err
+	}
+	r.link = link
+
+	return nil
+}
+
+func (r *resolvedDNS) apply(manager dbus.BusObject, link *net.Interface, dns []resolvedAddress, domains []resolvedDomain) error {
+	if err := manager.Call("org.freedesktop.resolve1.Manager.SetLinkDNS", 0, int32(link.Index), dns).Err; err != nil {
+		return fmt.Errorf("SetLinkDNS: %v", err)
+	}
+
+	if err := manager.Call("org.freedesktop.resolve1.Manager.SetLinkDomains", 0, int32(link.Index), domains).Err; err != nil {
+		return fmt.Errorf("SetLinkDomains: %v", err)
+	}
+
+	return nil
+}
+
+func (r *resolvedDNS) Set(link *net.Interface, nameServers []string, domain string, save bool) error {
+	manager, err := r.manager()
+	if err != nil {
+		return err
+	}
+
+	if r.link != nil && (save || r.link.Index != link.Index) {
+		if err := r.Revert(); err != nil {
+			return err
+		}
+	}
+
+	if r.link == nil {
+		if err := r.save(manager, link); err != nil {
+			return err
+		}
+	}
+
+	var dns []resolvedAddress
+	for _, ns := range nameServers {
+		ip := net.ParseIP(ns)
+		if ip4 := ip.To4(); ip4 != nil {
+			dns = append(dns, resolvedAddress{Family: 2, Address: ip4})
+		} else {
+			dns = append(dns, resolvedAddress{Family: 10, Address: ip.To16()})
+		}
+	}
+
+	domains := []resolvedDomain{}
+	if domain != "" {
+		domains = append(domains, resolvedDomain{Domain: domain})
+	}
+
+	return r.apply(manager, link, dns, domains)
+}
+
+func (r *resolvedDNS) Revert() error {
+	if r.link == nil {
+		return fmt.Errorf("no saved DNS configuration to revert to")
+	}
+
+	manager, err := r.manager()
+	if err != nil {
+		return err
+	}
+
+	// Nothing was configured on the link before, let systemd-resolved reset it.
+	if len(r.dns) == 0 && len(r.domains) == 0 {
+		err = manager.Call("org.freedesktop.resolve1.Manager.RevertLink", 0, int32(r.link.Index)).Err
+	} else {
+		domains := r.domains
+		if domains == nil {
+			domains = []resolvedDomain{}
+		}
+		err = r.apply(manager, r.link, r.dns, domains)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.link, r.dns, r.domains = nil, nil, nil
+
+	return nil
+}
+
+// networkManagerDNS reapplies the active connection of NetworkManager
+type networkManagerDNS struct {
+	device dbus.BusObject
+	saved  map[string]map[string]dbus.Variant
+}
+
+func (n *networkManagerDNS) Name() string {
+	return "NetworkManager"
+}
+
+func (n *networkManagerDNS) appliedConnection(device dbus.BusObject) (map[string]map[string]dbus.Variant, uint64, error) {
+	var settings map[string]map[string]dbus.Variant
+	var version uint64
+
+	err := device.Call("org.freedesktop.NetworkManager.Device.GetAppliedConnection", 0, uint32(0)).Store(&settings, &version)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetAppliedConnection: %v", err)
+	}
+
+	// Deprecated properties are rejected by Reapply along with their replacements.
+	for _, family := range []string{"ipv4", "ipv6"} {
+		delete(settings[family], "addresses")
+		delete(settings[family], "routes")
+	}
+
+	return settings, version, nil
+}
+
+func (n *networkManagerDNS) Set(link *net.Interface, nameServers []string, domain string, save bool) error {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return err
+	}
+
+	var devicePath dbus.ObjectPath
+	err = conn.Object("org.freedesktop.NetworkManager", "/org/freedesktop/NetworkManager").
+		Call("org.freedesktop.NetworkManager.GetDeviceByIpIface", 0, link.Name).Store(&devicePath)
+	if err != nil {
+		return fmt.Errorf("GetDeviceByIpIface %s: %v", link.Name, err)
+	}
+	device := conn.Object("org.freedesktop.NetworkManager", devicePath)
+
+	if n.saved != nil && (save || n.device.Path() != devicePath) {
+		if err := n.Revert(); err != nil {
+			return err
+		}
+	}
+
+	if n.saved == nil {
+		saved, _, err := n.appliedConnection(device)
+		if err != nil {
+			return err
+		}
+		n.device, n.saved = device, saved
+	}
+
+	settings, version, err := n.appliedConnection(device)
+	if err != nil {
+		return err
+	}
+
+	var dns4 []uint32
+	var dns6 [][]byte
+	for _, ns := range nameServers {
+		ip := net.ParseIP(ns)
+		if ip4 := ip.To4(); ip4 != nil {
+			// in_addr, thus in network byte order in memory
+			dns4 = append(dns4, uint32(ip4[0])|uint32(ip4[1])<<8|uint32(ip4[2])<<16|uint32(ip4[3])<<24)
+		} else {
+			dns6 = append(dns6, ip.To16())
+		}
+	}
+
+	search := []string{}
+	if domain != "" {
+		search = append(search, domain)
+	}
+
+	for family, dns := range map[string]interface{}{"ipv4": dns4, "ipv6": dns6} {
+		if settings[family] == nil {
+			settings[family] = map[string]dbus.Variant{}
+		}
+		if method, ok := settings[family]["method"]; ok && method.Value() == "disabled" {
+			continue
+		}
+		settings[family]["dns"] = dbus.MakeVariant(dns)
+		settings[family]["dns-search"] = dbus.MakeVariant(search)
+		settings[family]["ignore-auto-dns"] = dbus.MakeVariant(true)
+		// Negative priority excludes name servers of other connections.
+		settings[family]["dns-priority"] = dbus.MakeVariant(int32(-50))
+	}
+
+	err = device.Call("org.freedesktop.NetworkManager.Device.Reapply", 0, settings, version, uint32(0)).Err
+	if err != nil {
+		return fmt.Errorf("Reapply: %v", err)
+	}
+
+	return nil
+}
+
+func (n *networkManagerDNS) Revert() error {
+	if n.saved == nil {
+		return fmt.Errorf("no saved DNS configuration to revert to")
+	}
+
+	// Version 0 reapplies regardless of changes made since.
+	err := n.device.Call("org.freedesktop.NetworkManager.Device.Reapply", 0, n.saved, uint64(0), uint32(0)).Err
+	if err != nil {
+		return fmt.Errorf("Reapply: %v", err)
+	}
+
+	n.device, n.saved = nil, nil
+
+	return nil
+}
+
+// resolvconfDNS adds a record for the tunnel with resolvconf(8)
+type resolvconfDNS struct{}
+
+func (r *resolvconfDNS) Name() string {
+	return "resolvconf"
+}
+
+func (r *resolvconfDNS) Set(link *net.Interface, nameServers []string, domain string, save bool) error {
+	cmd := exec.Command("resolvconf", "-a", resolvconfRecord)
+	cmd.Stdin = strings.NewReader(resolvConfContent(nameServers, domain))
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("resolvconf -a %s: %v: %s", resolvconfRecord, err, bytes.TrimSpace(output))
+	}
+
+	return nil
+}
+
+func (r *resolvconfDNS) Revert() error {
+	if output, err := exec.Command("resolvconf", "-d", resolvconfRecord).CombinedOutput(); err != nil {
+		return fmt.Errorf("resolvconf -d %s: %v: %s", resolvconfRecord, err, bytes.TrimSpace(output))
+	}
+
+	return nil
+}
+
+// resolvConfDNS writes /etc/resolv.conf, keeping the original until reverted
+type resolvConfDNS struct {
+	path   string
+	backup string
+}
+
+func newResolvConfDNS() *resolvConfDNS {
+	return &resolvConfDNS{path: resolvConf, backup: resolvConf + ".nerf"}
+}
+
+func (r *resolvConfDNS) Name() string {
+	return "resolv.conf"
+}
+
+func (r *resolvConfDNS) Set(link *net.Interface, nameServers []string, domain string, save bool) error {
+	// The backup left by a crash is the original, don't overwrite it.
+	if _, err := os.Lstat(r.backup); os.IsNotExist(err) {
+		if err := os.Rename(r.path, r.backup); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return writeFileAtomic(r.path, []byte(resolvConfContent(nameServers, domain)), 0644)
+}
+
+func (r *resolvConfDNS) Revert() error {
+	if _, err := os.Lstat(r.backup); err != nil {
+		return fmt.Errorf("no backup of %s to revert to: %v", r.path, err)
+	}
+
+	return os.Rename(r.backup, r.path)
+}
+
+// resolvConfContent formats name servers and the search domain for resolv.conf
+func resolvConfContent(nameServers []string, domain string) string {
+	var content strings.Builder
+
+	content.WriteString("# Generated by Nerf!\n")
+	for _, ns := range nameServers {
+		content.WriteString("nameserver " + ns + "\n")
+	}
+	if domain != "" {
+		content.WriteString("search " + domain + "\n")
+	}
+
+	return content.String()
+}
@@ -0,0 +1,122 @@
+package nerf
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestResolvConfContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		nameServers []string
+		domain      string
+		want        string
+	}{
+		{
+			name:        "name servers and domain",
+			nameServers: []string{"10.0.0.1", "fd00::1"},
+			domain:      "example.com",
+			want:        "# Generated by Nerf!\nnameserver 10.0.0.1\nnameserver fd00::1\nsearch example.com\n",
+		},
+		{
+			name:        "no domain",
+			nameServers: []string{"10.0.0.1"},
+			want:        "# Generated by Nerf!\nnameserver 10.0.0.1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolvConfContent(tt.nameServers, tt.domain); got != tt.want {
+				t.Errorf("resolvConfContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvConfDNS(t *testing.T) {
+	original := "nameserver 192.0.2.53\n"
+
+	tests := []struct {
+		name     string
+		symlink  bool
+		leftover string
+		want     string
+	}{
+		{name: "file", want: original},
+		{name: "symlink", symlink: true, want: original},
+		{
+			// The backup left by a crash is the original, it's restored on revert.
+			name:     "leftover backup",
+			leftover: "nameserver 198.51.100.53\n",
+			want:     "nameserver 198.51.100.53\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			r := &resolvConfDNS{path: path.Join(dir, "resolv.conf"), backup: path.Join(dir, "resolv.conf.nerf")}
+
+			if tt.symlink {
+				target := path.Join(dir, "stub-resolv.conf")
+				if err := ioutil.WriteFile(target, []byte(original), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(target, r.path); err != nil {
+					t.Fatal(err)
+				}
+			} else if err := ioutil.WriteFile(r.path, []byte(original), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.leftover != "" {
+				if err := ioutil.WriteFile(r.backup, []byte(tt.leftover), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := r.Set(nil, []string{"10.0.0.1"}, "example.com", true); err != nil {
+				t.Fatal(err)
+			}
+			// Migrating to another endpoint keeps the backup.
+			if err := r.Set(nil, []string{"10.0.0.2"}, "example.com", false); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ioutil.ReadFile(r.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := resolvConfContent([]string{"10.0.0.2"}, "example.com"); string(data) != want {
+				t.Errorf("resolv.conf = %q, want %q", data, want)
+			}
+			if info, err := os.Lstat(r.path); err != nil || info.Mode()&os.ModeSymlink != 0 {
+				t.Errorf("resolv.conf is not a regular file: %v", err)
+			}
+
+			if err := r.Revert(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err = ioutil.ReadFile(r.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("reverted resolv.conf = %q, want %q", data, tt.want)
+			}
+			if info, err := os.Lstat(r.path); err != nil || (info.Mode()&os.ModeSymlink != 0) != tt.symlink {
+				t.Errorf("reverted resolv.conf symlink = %t, want %t", !tt.symlink, tt.symlink)
+			}
+			if _, err := os.Lstat(r.backup); !os.IsNotExist(err) {
+				t.Errorf("backup left after revert: %v", err)
+			}
+
+			if err := r.Revert(); err == nil {
+				t.Error("Revert() without a backup succeeded")
+			}
+		})
+	}
+}
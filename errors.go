@@ -0,0 +1,60 @@
+package nerf
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of ErrorInfo details attached to gRPC status errors
+const ErrorDomain = "nerf"
+
+// Reasons of failures reported in ErrorInfo details of gRPC status errors
+const (
+	ReasonAlreadyConnected  = "ALREADY_CONNECTED"
+	ReasonNoEndpoints       = "NO_ENDPOINTS"
+	ReasonRouteFailed       = "ROUTE_FAILED"
+	ReasonServerUnavailable = "SERVER_UNAVAILABLE"
+	ReasonTeamsNotSynced    = "TEAMS_NOT_SYNCED"
+	ReasonServerDraining    = "SERVER_DRAINING"
+	ReasonUnauthenticated   = "UNAUTHENTICATED"
+	ReasonNotTeamMember     = "NOT_TEAM_MEMBER"
+	ReasonNoIPAddress       = "NO_IP_ADDRESS"
+	ReasonConfigFailed      = "CONFIG_FAILED"
+	ReasonDNSFailed         = "DNS_FAILED"
+	ReasonNebulaFailed      = "NEBULA_FAILED"
+	ReasonInterrupted       = "INTERRUPTED"
+	ReasonCanceled          = "CANCELED"
+	ReasonNotConnecting     = "NOT_CONNECTING"
+	ReasonNotSessionOwner   = "NOT_SESSION_OWNER"
+)
+
+// NewError creates a gRPC status error with the reason in ErrorInfo details
+func NewError(code codes.Code, reason string, message string, metadata map[string]string) error {
+	st, err := status.New(code, message).WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   ErrorDomain,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return status.Error(code, message)
+	}
+
+	return st.Err()
+}
+
+// ErrorReason returns the reason of the error created by NewError
+func ErrorReason(err error) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+			return info.Reason
+		}
+	}
+
+	return ""
+}
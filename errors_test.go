@@ -0,0 +1,65 @@
+package nerf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewError(t *testing.T) {
+	err := NewError(codes.Unavailable, ReasonNoEndpoints, "Can't discover endpoints", map[string]string{
+		"error": "timeout",
+	})
+
+	st := status.Convert(err)
+	if st.Code() != codes.Unavailable {
+		t.Errorf("code = %s, want %s", st.Code(), codes.Unavailable)
+	}
+	if st.Message() != "Can't discover endpoints" {
+		t.Errorf("message = %q", st.Message())
+	}
+
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("got %d details, want 1", len(details))
+	}
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	if !ok {
+		t.Fatalf("details are %T, want ErrorInfo", details[0])
+	}
+	if info.Domain != ErrorDomain || info.Reason != ReasonNoEndpoints || info.Metadata["error"] != "timeout" {
+		t.Errorf("ErrorInfo = %+v", info)
+	}
+}
+
+func TestErrorReason(t *testing.T) {
+	foreign, _ := status.New(codes.Internal, "foreign").WithDetails(&errdetails.ErrorInfo{
+		Reason: ReasonNoEndpoints,
+		Domain: "example.com",
+	})
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "plain error", err: errors.New("failed"), want: ""},
+		{name: "status without details", err: status.Error(codes.Internal, "failed"), want: ""},
+		{name: "foreign domain", err: foreign.Err(), want: ""},
+		{name: "nerf error", err: NewError(codes.Aborted, ReasonCanceled, "cancelled", nil), want: ReasonCanceled},
+		{name: "interrupted", err: interrupted(fmt.Errorf("disconnected")), want: ReasonInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorReason(tt.err); got != tt.want {
+				t.Errorf("ErrorReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package nerf
+
+import (
+	"sync"
+	"time"
+)
+
+// EventHub fans out events to subscribers of WatchEvents streams
+type EventHub struct {
+	mutex       sync.Mutex
+	subscribers map[chan *Event]string
+}
+
+// NewEventHub initializes EventHub
+func NewEventHub() *EventHub {
+	return &EventHub{
+		subscribers: make(map[chan *Event]string),
+	}
+}
+
+// NewEvent creates an event of the given type with a user-readable message
+func NewEvent(eventType Event_Type, message string) *Event {
+	return &Event{
+		Type:      eventType,
+		Message:   message,
+		Timestamp: time.Now().Unix(),
+	}
+}
+
+// Subscribe returns a channel to receive events for the login, empty login receives all
+func (h *EventHub) Subscribe(login string) chan *Event {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	ch := make(chan *Event, 16)
+	h.subscribers[ch] = login
+
+	return ch
+}
+
+// Unsubscribe stops delivering events to the channel
+func (h *EventHub) Unsubscribe(ch chan *Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.subscribers, ch)
+}
+
+// Subscribers returns the number of subscribers
+func (h *EventHub) Subscribers() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return len(h.subscribers)
+}
+
+// Publish sends the event to subscribers of the login, empty login broadcasts it
+func (h *EventHub) Publish(login string, event *Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for ch, subscriber := range h.subscribers {
+		if login != "" && subscriber != "" && subscriber != login {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
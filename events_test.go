@@ -0,0 +1,110 @@
+package nerf
+
+import (
+	"testing"
+)
+
+func received(ch chan *Event) []string {
+	var messages []string
+
+	for {
+		select {
+		case event := <-ch:
+			messages = append(messages, event.Message)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestEventHubPublish(t *testing.T) {
+	tests := []struct {
+		name  string
+		login string
+		want  map[string][]string
+	}{
+		{
+			name:  "broadcast",
+			login: "",
+			want: map[string][]string{
+				"alice": {"message"},
+				"bob":   {"message"},
+				"":      {"message"},
+			},
+		},
+		{
+			name:  "single user",
+			login: "alice",
+			want: map[string][]string{
+				"alice": {"message"},
+				"bob":   nil,
+				"":      {"message"},
+			},
+		},
+		{
+			name:  "unknown user",
+			login: "eve",
+			want: map[string][]string{
+				"alice": nil,
+				"bob":   nil,
+				"":      {"message"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewEventHub()
+			subscribers := map[string]chan *Event{}
+			for login := range tt.want {
+				subscribers[login] = hub.Subscribe(login)
+			}
+
+			hub.Publish(tt.login, NewEvent(Event_TEAMS_CHANGED, "message"))
+
+			for login, want := range tt.want {
+				got := received(subscribers[login])
+				if len(got) != len(want) {
+					t.Errorf("subscriber %q received %v, want %v", login, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEventHubUnsubscribe(t *testing.T) {
+	hub := NewEventHub()
+
+	first := hub.Subscribe("alice")
+	second := hub.Subscribe("alice")
+	if n := hub.Subscribers(); n != 2 {
+		t.Fatalf("Subscribers() = %d, want 2", n)
+	}
+
+	hub.Unsubscribe(first)
+	if n := hub.Subscribers(); n != 1 {
+		t.Fatalf("Subscribers() = %d, want 1", n)
+	}
+
+	hub.Publish("alice", NewEvent(Event_TEAMS_CHANGED, "message"))
+	if got := received(first); len(got) != 0 {
+		t.Errorf("unsubscribed channel received %v", got)
+	}
+	if got := received(second); len(got) != 1 {
+		t.Errorf("subscribed channel received %v, want one event", got)
+	}
+}
+
+func TestEventHubSlowSubscriber(t *testing.T) {
+	hub := NewEventHub()
+	slow := hub.Subscribe("")
+
+	// Publishing never blocks, the events not fitting the buffer are dropped.
+	for i := 0; i < cap(slow)+10; i++ {
+		hub.Publish("", NewEvent(Event_CONFIG_AVAILABLE, "message"))
+	}
+
+	if got := received(slow); len(got) != cap(slow) {
+		t.Errorf("received %d events, want %d", len(got), cap(slow))
+	}
+}
@@ -0,0 +1,437 @@
+package nerf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+// Helper performs privileged operations on behalf of nerf-api
+type Helper struct {
+	mutex  sync.Mutex
+	nebula *helperProcess
+}
+
+// helperProcess is Nebula started by nerf-helper
+type helperProcess struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+// nebulaLogWriter forwards the output of Nebula to nerf-api line by line
+type nebulaLogWriter struct {
+	stream string
+	buffer []byte
+	send   func(output *HelperNebulaOutput) error
+}
+
+func (w *nebulaLogWriter) Write(p []byte) (int, error) {
+	w.buffer = append(w.buffer, p...)
+
+	for {
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(w.buffer[:i]); len(line) > 0 {
+			_ = w.send(&HelperNebulaOutput{Stream: w.stream, Line: string(line)})
+		}
+		w.buffer = w.buffer[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// HelperSocket returns the path of nerf-helper UNIX socket
+func HelperSocket() string {
+	return path.Join(HelperDir(), "helper.sock")
+}
+
+// helperNebulaConfig is the path of config.yml of the running Nebula
+func helperNebulaConfig() string {
+	return path.Join(HelperDir(), "config.yml")
+}
+
+// helperNebulaExecutable is the path of the verified copy of Nebula
+func helperNebulaExecutable() string {
+	return path.Join(HelperDir(), "nebula")
+}
+
+// HelperCredentials returns server credentials authorizing root and Cfg.HelperUser
+func HelperCredentials() credentials.TransportCredentials {
+	return peerCredentials{authorize: authorizeHelperPeer}
+}
+
+func authorizeHelperPeer(info PeerAuthInfo) error {
+	if info.UID == 0 {
+		return nil
+	}
+
+	u, err := user.Lookup(Cfg.HelperUser)
+	if err != nil {
+		return err
+	}
+	if strconv.FormatUint(uint64(info.UID), 10) == u.Uid {
+		return nil
+	}
+
+	return fmt.Errorf("UID %d is not allowed to use nerf-helper", info.UID)
+}
+
+// helperEndpoint validates the IP address of the endpoint
+func helperEndpoint(remoteIP string) (*Endpoint, error) {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil || ip.To4() == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid IPv4 address %q", remoteIP)
+	}
+
+	return &Endpoint{RemoteIP: ip.String()}, nil
+}
+
+// AddRoute creates a static route towards nerf-server via the default route
+func (h *Helper) AddRoute(ctx context.Context, in *HelperRoute) (*empty.Empty, error) {
+	e, err := helperEndpoint(in.RemoteIP)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return &empty.Empty{}, NebulaAddLightHouseStaticRoute(e)
+}
+
+// DeleteRoute deletes the static route towards nerf-server
+func (h *Helper) DeleteRoute(ctx context.Context, in *HelperRoute) (*empty.Empty, error) {
+	e, err := helperEndpoint(in.RemoteIP)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return &empty.Empty{}, NebulaDeleteLightHouseStaticRoute(e)
+}
+
+// SetNameServers sets name servers, saving the previous ones to revert to if asked
+func (h *Helper) SetNameServers(ctx context.Context, in *HelperNameServers) (*empty.Empty, error) {
+	e, err := helperEndpoint(in.RemoteIP)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(in.NameServers) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no name servers")
+	}
+	for _, ns := range in.NameServers {
+		if net.ParseIP(ns) == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid name server %q", ns)
+		}
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return &empty.Empty{}, NebulaSetNameServers(e, in.NameServers, in.Save)
+}
+
+// RevertNameServers reverts name servers changed by SetNameServers
+func (h *Helper) RevertNameServers(ctx context.Context, in *HelperRoute) (*empty.Empty, error) {
+	e, err := helperEndpoint(in.RemoteIP)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return &empty.Empty{}, NebulaRevertNameServers(e)
+}
+
+// StartNebula starts Nebula and streams its output until it exits
+func (h *Helper) StartNebula(in *HelperNebula, stream Helper_StartNebulaServer) error {
+	var sendMutex sync.Mutex
+	send := func(output *HelperNebulaOutput) error {
+		sendMutex.Lock()
+		defer sendMutex.Unlock()
+		return stream.Send(output)
+	}
+
+	h.mutex.Lock()
+	if h.nebula != nil {
+		h.mutex.Unlock()
+		return status.Error(codes.FailedPrecondition, "Nebula is already running")
+	}
+
+	p, err := h.startNebula(in.Config, send)
+	if err != nil {
+		h.mutex.Unlock()
+		return err
+	}
+	h.nebula = p
+	h.mutex.Unlock()
+
+	pid := int32(p.cmd.Process.Pid)
+	Cfg.Logger.Info("started Nebula", zap.Int32("Pid", pid))
+	_ = send(&HelperNebulaOutput{Pid: pid})
+
+	go func() {
+		select {
+		case <-stream.Context().Done():
+			Cfg.Logger.Warn("nerf-api went away, stopping Nebula", zap.Int32("Pid", pid))
+			if err := p.stop(Cfg.NebulaStopTimeout); err != nil {
+				Cfg.Logger.Error("can't stop Nebula", zap.Error(err))
+			}
+		case <-p.done:
+		}
+	}()
+
+	err = p.cmd.Wait()
+	close(p.done)
+
+	h.mutex.Lock()
+	h.nebula = nil
+	h.mutex.Unlock()
+
+	Cfg.Logger.Info("Nebula exited", zap.Int32("Pid", pid), zap.Error(err))
+
+	output := &HelperNebulaOutput{Pid: pid, Exited: true}
+	if err != nil {
+		output.Error = err.Error()
+	}
+	_ = send(output)
+
+	return nil
+}
+
+func (h *Helper) startNebula(config string, send func(*HelperNebulaOutput) error) (*helperProcess, error) {
+	if err := helperInstallNebula(); err != nil {
+		return nil, err
+	}
+
+	sanitized, err := helperSanitizeConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeFileAtomic(helperNebulaConfig(), sanitized, 0600); err != nil {
+		return nil, fmt.Errorf("can't write Nebula config: %s", err)
+	}
+
+	cmd, err := nebulaCommand(helperNebulaConfig())
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = &nebulaLogWriter{stream: "stdout", send: send}
+	cmd.Stderr = &nebulaLogWriter{stream: "stderr", send: send}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &helperProcess{cmd: cmd, done: make(chan struct{})}, nil
+}
+
+// helperNebulaConfigSections are the sections of config.yml passed to Nebula
+var helperNebulaConfigSections = map[string]bool{
+	"pki":              true,
+	"static_host_map":  true,
+	"lighthouse":       true,
+	"listen":           true,
+	"punchy":           true,
+	"local_range":      true,
+	"preferred_ranges": true,
+	"tun":              true,
+	"firewall":         true,
+	"cipher":           true,
+	"handshakes":       true,
+	"logging":          true,
+}
+
+// helperSanitizeConfig allows only known sections and inline certificates
+func helperSanitizeConfig(config string) ([]byte, error) {
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal([]byte(config), &parsed); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid Nebula config: %v", err)
+	}
+
+	for section := range parsed {
+		if !helperNebulaConfigSections[section] {
+			return nil, status.Errorf(codes.InvalidArgument, "section %q of Nebula config is not allowed", section)
+		}
+	}
+
+	pki, ok := parsed["pki"].(map[interface{}]interface{})
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "no pki section in Nebula config")
+	}
+	for key, value := range pki {
+		switch key {
+		case "ca", "cert", "key":
+			if pem, ok := value.(string); !ok || !strings.Contains(pem, "-----BEGIN") {
+				return nil, status.Errorf(codes.InvalidArgument, "pki.%s of Nebula config must be inline PEM", key)
+			}
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "pki.%v of Nebula config is not allowed", key)
+		}
+	}
+
+	return yaml.Marshal(parsed)
+}
+
+// helperInstallNebula copies Nebula into HelperDir and verifies the copy
+func helperInstallNebula() error {
+	binary, err := ioutil.ReadFile(NebulaExecutable())
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(helperNebulaExecutable(), binary, 0755); err != nil {
+		return fmt.Errorf("can't copy Nebula: %s", err)
+	}
+
+	binary, err = ioutil.ReadFile(helperNebulaExecutable())
+	if err != nil {
+		return err
+	}
+
+	var signature []byte
+	if NebulaSigningKey != "" {
+		if signature, err = ioutil.ReadFile(nebulaSignature()); err != nil {
+			return err
+		}
+	}
+
+	if err := helperNebulaVerify(binary, signature); err != nil {
+		_ = os.Remove(helperNebulaExecutable())
+		return err
+	}
+
+	return nil
+}
+
+// StopNebula stops Nebula with SIGTERM, and SIGKILL after the timeout
+func (h *Helper) StopNebula(ctx context.Context, in *HelperNebulaStop) (*empty.Empty, error) {
+	h.mutex.Lock()
+	p := h.nebula
+	h.mutex.Unlock()
+
+	if p == nil || int32(p.cmd.Process.Pid) != in.Pid {
+		return nil, status.Errorf(codes.NotFound, "Nebula with PID %d is not running", in.Pid)
+	}
+
+	timeout := time.Duration(in.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = Cfg.NebulaStopTimeout
+	}
+
+	return &empty.Empty{}, p.stop(timeout)
+}
+
+// Stop stops Nebula when nerf-helper is shutting down
+func (h *Helper) Stop() {
+	h.mutex.Lock()
+	p := h.nebula
+	h.mutex.Unlock()
+
+	if p == nil {
+		return
+	}
+
+	if err := p.stop(Cfg.NebulaStopTimeout); err != nil {
+		Cfg.Logger.Error("can't stop Nebula", zap.Error(err))
+	}
+}
+
+func (p *helperProcess) stop(timeout time.Duration) error {
+	// Already exited.
+	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		<-p.done
+		return nil
+	}
+
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	if err := p.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	<-p.done
+
+	return fmt.Errorf("nebula did not stop in %s, killed", timeout)
+}
+
+// helperError returns the message of the error reported by nerf-helper
+func helperError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("nerf-helper: %s", status.Convert(err).Message())
+}
+
+// helperAddRoute creates a static route towards the endpoint via nerf-helper
+func helperAddRoute(e *Endpoint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := Cfg.Helper.AddRoute(ctx, &HelperRoute{RemoteIP: e.RemoteIP})
+
+	return helperError(err)
+}
+
+// helperDeleteRoute deletes the static route towards the endpoint via nerf-helper
+func helperDeleteRoute(e *Endpoint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := Cfg.Helper.DeleteRoute(ctx, &HelperRoute{RemoteIP: e.RemoteIP})
+
+	return helperError(err)
+}
+
+// helperSetNameServers sets name servers via nerf-helper
+func helperSetNameServers(e *Endpoint, nameServers []string, save bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := Cfg.Helper.SetNameServers(ctx, &HelperNameServers{
+		RemoteIP:    e.RemoteIP,
+		NameServers: nameServers,
+		Save:        save,
+	})
+
+	return helperError(err)
+}
+
+// helperRevertNameServers reverts name servers via nerf-helper
+func helperRevertNameServers(e *Endpoint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := Cfg.Helper.RevertNameServers(ctx, &HelperRoute{RemoteIP: e.RemoteIP})
+
+	return helperError(err)
+}
@@ -0,0 +1,15 @@
+package nerf
+
+import (
+	"os/exec"
+)
+
+// HelperDir is the root-owned runtime directory of nerf-helper
+func HelperDir() string {
+	return "/var/run/nerf-helper"
+}
+
+// nebulaCommand runs Nebula as root, utun devices can't be created otherwise
+func nebulaCommand(config string) (*exec.Cmd, error) {
+	return exec.Command(helperNebulaExecutable(), "-config", config), nil
+}
@@ -0,0 +1,44 @@
+package nerf
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// HelperDir is the root-owned runtime directory of nerf-helper
+func HelperDir() string {
+	return "/run/nerf-helper"
+}
+
+// nebulaCommand runs Nebula as Cfg.HelperUser with CAP_NET_ADMIN only
+func nebulaCommand(config string) (*exec.Cmd, error) {
+	u, err := user.Lookup(Cfg.HelperUser)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, err
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chown(config, uid, gid); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(helperNebulaExecutable(), "-config", config)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential:  &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)},
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,72 @@
+package nerf
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const testNebulaConfig = `# Generated by Nerf!
+
+pki:
+  ca: |
+    -----BEGIN NEBULA CERTIFICATE-----
+    ca
+    -----END NEBULA CERTIFICATE-----
+  cert: |
+    -----BEGIN NEBULA CERTIFICATE-----
+    cert
+    -----END NEBULA CERTIFICATE-----
+  key: |
+    -----BEGIN NEBULA X25519 PRIVATE KEY-----
+    key
+    -----END NEBULA X25519 PRIVATE KEY-----
+static_host_map:
+  "10.0.0.1": ["192.0.2.1:4242"]
+
+lighthouse:
+  am_lighthouse: false
+  hosts:
+    - 10.0.0.1
+
+tun:
+  dev: nebula1
+`
+
+func TestHelperSanitizeConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "generated", config: testNebulaConfig},
+		{name: "sshd", config: testNebulaConfig + "sshd:\n  enabled: true\n  listen: 127.0.0.1:2222\n", wantErr: true},
+		{name: "stats", config: testNebulaConfig + "stats:\n  type: prometheus\n  listen: 0.0.0.0:8080\n", wantErr: true},
+		{name: "unknown pki key", config: strings.Replace(testNebulaConfig, "pki:\n", "pki:\n  blocklist: []\n", 1), wantErr: true},
+		{name: "certificate file only", config: strings.Replace(testNebulaConfig, "  ca: |\n    -----BEGIN NEBULA CERTIFICATE-----\n    ca\n    -----END NEBULA CERTIFICATE-----\n", "  ca: /etc/nebula/ca.crt\n", 1), wantErr: true},
+		{name: "no pki", config: "tun:\n  dev: nebula1\n", wantErr: true},
+		{name: "invalid", config: "pki: [", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := helperSanitizeConfig(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("helperSanitizeConfig() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var config map[string]interface{}
+			if err := yaml.Unmarshal(got, &config); err != nil {
+				t.Fatal(err)
+			}
+			pki := config["pki"].(map[interface{}]interface{})
+			if !strings.Contains(pki["key"].(string), "-----BEGIN NEBULA X25519 PRIVATE KEY-----") {
+				t.Errorf("helperSanitizeConfig() = %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package nerf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// NebulaManifestURL compile-time derived from -X github.com/ton31337/nerf.NebulaManifestURL
+var NebulaManifestURL string
+
+// parseNebulaManifest parses the manifest of Nebula releases in JSON
+func parseNebulaManifest(data []byte) (*NebulaManifest, error) {
+	manifest := &NebulaManifest{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid Nebula manifest: %v", err)
+	}
+
+	return manifest, nil
+}
+
+// NebulaFetchManifest downloads the manifest of Nebula releases
+func NebulaFetchManifest(ctx context.Context, url string) (*NebulaManifest, error) {
+	data, err := nebulaFetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNebulaManifest(data)
+}
+
+// NebulaLoadManifest reads the manifest of Nebula releases from a file
+func NebulaLoadManifest(file string) (*NebulaManifest, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNebulaManifest(data)
+}
+
+// compareVersions compares dotted versions numerically
+func compareVersions(a string, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// latestNebulaRelease returns the most recent release for the platform
+func latestNebulaRelease(manifest *NebulaManifest, goos string, goarch string) *NebulaRelease {
+	var latest *NebulaRelease
+
+	for _, release := range manifest.Releases {
+		if release.Os != goos || release.Arch != goarch {
+			continue
+		}
+		if latest == nil || compareVersions(release.Version, latest.Version) > 0 {
+			latest = release
+		}
+	}
+
+	return latest
+}
+
+// NebulaVersion detects the version of the installed Nebula
+func NebulaVersion(ctx context.Context) (string, error) {
+	if err := NebulaVerify(); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, NebulaExecutable(), "-version").Output()
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "Version:" {
+			return fields[1], nil
+		}
+	}
+
+	return "", fmt.Errorf("can't detect Nebula version from %q", output)
+}
+
+// NebulaUpgrade installs the latest release if the installed one is older than minVersion
+func NebulaUpgrade(ctx context.Context, manifest *NebulaManifest) error {
+	installed, ok := nebulaUpToDate(ctx, manifest.MinVersion)
+	if ok {
+		return nil
+	}
+
+	return nebulaInstallRelease(ctx, manifest, installed)
+}
+
+// nebulaUpToDate returns the installed version, and whether it's at least minVersion
+func nebulaUpToDate(ctx context.Context, minVersion string) (string, bool) {
+	installed, err := NebulaVersion(ctx)
+	if err != nil {
+		Cfg.Logger.Debug("can't detect installed Nebula version", zap.Error(err))
+		return "", false
+	}
+
+	return installed, compareVersions(installed, minVersion) >= 0
+}
+
+// nebulaInstallRelease installs the most recent release of the manifest for this platform
+func nebulaInstallRelease(ctx context.Context, manifest *NebulaManifest, installed string) error {
+	release := latestNebulaRelease(manifest, runtime.GOOS, runtime.GOARCH)
+	if release == nil {
+		return fmt.Errorf("no Nebula release for %s/%s in the manifest", runtime.GOOS, runtime.GOARCH)
+	}
+	if compareVersions(release.Version, manifest.MinVersion) < 0 {
+		return fmt.Errorf("Nebula %s is required, but %s is the latest for %s/%s",
+			manifest.MinVersion, release.Version, runtime.GOOS, runtime.GOARCH)
+	}
+
+	Cfg.Logger.Info("upgrading Nebula",
+		zap.String("From", installed),
+		zap.String("To", release.Version),
+		zap.String("MinVersion", manifest.MinVersion))
+
+	return nebulaInstall(ctx, release.Url, release.Sha256)
+}
+
+// nebulaCheckManifestKeys checks releases of a manifest can be verified
+func nebulaCheckManifestKeys() error {
+	if Cfg.NebulaSigningKey == "" {
+		return fmt.Errorf("Nebula manifest requires a signing key")
+	}
+	if Cfg.NebulaSHA256 != "" {
+		return fmt.Errorf("Nebula manifest can't be used with a pinned checksum, releases never match it")
+	}
+
+	return nil
+}
+
+// nebulaServerUpgrade upgrades Nebula if nerf-server requires a newer version
+func nebulaServerUpgrade(ctx context.Context, manifest *NebulaManifest) error {
+	if manifest == nil || manifest.MinVersion == "" {
+		return nil
+	}
+
+	if Cfg.NebulaManifestURL != "" {
+		m, err := NebulaFetchManifest(ctx, Cfg.NebulaManifestURL)
+		if err != nil {
+			return err
+		}
+		return NebulaUpgrade(ctx, &NebulaManifest{MinVersion: manifest.MinVersion, Releases: m.Releases})
+	}
+
+	installed, ok := nebulaUpToDate(ctx, manifest.MinVersion)
+	if ok {
+		return nil
+	}
+	if err := nebulaCheckManifestKeys(); err != nil {
+		return fmt.Errorf("refusing to install Nebula %s from the manifest of nerf-server: %v",
+			manifest.MinVersion, err)
+	}
+
+	return nebulaInstallRelease(ctx, manifest, installed)
+}
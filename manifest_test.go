@@ -0,0 +1,145 @@
+package nerf
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{a: "1.5.2", b: "1.5.2", want: 0},
+		{a: "v1.5.2", b: "1.5.2", want: 0},
+		{a: "1.5", b: "1.5.0", want: 0},
+		{a: "1.10.0", b: "1.9.2", want: 1},
+		{a: "1.9.2", b: "1.10.0", want: -1},
+		{a: "2.0.0", b: "1.99.99", want: 1},
+		{a: "1.5.0", b: "1.5.1", want: -1},
+		{a: "", b: "1.0.0", want: -1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLatestNebulaRelease(t *testing.T) {
+	manifest, err := parseNebulaManifest([]byte(`{
+		"minVersion": "1.5.0",
+		"releases": [
+			{"version": "1.9.0", "os": "linux", "arch": "amd64", "url": "https://example.com/1.9.0"},
+			{"version": "1.10.0", "os": "linux", "arch": "amd64", "url": "https://example.com/1.10.0"},
+			{"version": "1.11.0", "os": "linux", "arch": "arm64", "url": "https://example.com/arm64"},
+			{"version": "1.12.0", "os": "darwin", "arch": "amd64", "url": "https://example.com/darwin"}
+		],
+		"unknown": true
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		goos    string
+		goarch  string
+		wantURL string
+	}{
+		{goos: "linux", goarch: "amd64", wantURL: "https://example.com/1.10.0"},
+		{goos: "linux", goarch: "arm64", wantURL: "https://example.com/arm64"},
+		{goos: "darwin", goarch: "amd64", wantURL: "https://example.com/darwin"},
+		{goos: "darwin", goarch: "arm64", wantURL: ""},
+	}
+
+	for _, tt := range tests {
+		release := latestNebulaRelease(manifest, tt.goos, tt.goarch)
+		url := ""
+		if release != nil {
+			url = release.Url
+		}
+		if url != tt.wantURL {
+			t.Errorf("latestNebulaRelease(%s/%s) = %q, want %q", tt.goos, tt.goarch, url, tt.wantURL)
+		}
+	}
+}
+
+func TestNebulaServerUpgradeUnsigned(t *testing.T) {
+	pinNebula(t, "", "")
+	manifestURL := Cfg.NebulaManifestURL
+	Cfg.NebulaManifestURL = ""
+	defer func() {
+		Cfg.NebulaManifestURL = manifestURL
+	}()
+
+	var downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+	}))
+	defer server.Close()
+
+	if err := nebulaServerUpgrade(context.Background(), nil); err != nil {
+		t.Errorf("nebulaServerUpgrade() without a manifest error = %v", err)
+	}
+
+	// nerf-server can't get a binary installed by sending its checksum.
+	err := nebulaServerUpgrade(context.Background(), &NebulaManifest{
+		MinVersion: "99.0.0",
+		Releases: []*NebulaRelease{
+			{Version: "99.0.0", Os: "linux", Arch: "amd64", Url: server.URL, Sha256: sha256Hex(nil)},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "signing key") {
+		t.Errorf("nebulaServerUpgrade() error = %v, want refused without a signing key", err)
+	}
+	if n := atomic.LoadInt32(&downloads); n != 0 {
+		t.Errorf("downloaded %d times, want none", n)
+	}
+}
+
+func TestNebulaDownloadManifestKeys(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	manifestURL := Cfg.NebulaManifestURL
+	Cfg.NebulaManifestURL = server.URL
+	defer func() {
+		Cfg.NebulaManifestURL = manifestURL
+	}()
+
+	tests := []struct {
+		name    string
+		pinned  string
+		key     string
+		wantErr string
+	}{
+		{name: "no signing key", wantErr: "requires a signing key"},
+		{name: "pinned checksum", pinned: sha256Hex(nil), key: "key", wantErr: "pinned checksum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinNebula(t, tt.pinned, tt.key)
+
+			err := NebulaDownload(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NebulaDownload() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Releases nerf-helper would refuse to start are never fetched.
+	if n := atomic.LoadInt32(&fetches); n != 0 {
+		t.Errorf("manifest fetched %d times, want none", n)
+	}
+}
@@ -2,19 +2,20 @@ package nerf
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
-	"path"
 	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
 )
 
@@ -52,28 +53,14 @@ func NewCertificate(Ca string, Crt string, Key string) *Certificate {
 	}
 }
 
-// NebulaStart starts Nebula instance in foreground
-func NebulaStart() (int, error) {
-	var err error
-
-	cmd := exec.Command(NebulaExecutable(), "-config", path.Join(NebulaDir(), "config.yml"))
-	err = cmd.Start()
-	if err != nil {
-		return -1, err
-	}
-
-	go func() {
-		err = cmd.Wait()
-	}()
-
-	return cmd.Process.Pid, err
-}
-
 // NebulaGenerateConfig generate config.yml
-func NebulaGenerateConfig(userTeams []string) (string, error) {
+func NebulaGenerateConfig(ctx context.Context, userTeams []string) (string, error) {
 	var generatedConfig bytes.Buffer
 
-	NebulaGenerateCertificate(userTeams)
+	ctx, span := StartSpan(ctx, "config", LoginAttribute(ServerCfg.Login))
+	defer span.End()
+
+	NebulaGenerateCertificate(ctx, userTeams)
 
 	configTemplate := `# Generated by Nerf!
 
@@ -128,16 +115,20 @@ firewall:
 }
 
 // NebulaClientIP returns client's IP from IPAM
-func NebulaClientIP() (net.IPNet, error) {
+func NebulaClientIP(ctx context.Context) (net.IPNet, error) {
 	var gaidysResponse GaidysResponse
 	url := ServerCfg.GaidysUrl + "/api/v1/hostname/" + ServerCfg.Login
 
+	ctx, span := StartSpan(ctx, "ipam", LoginAttribute(ServerCfg.Login))
+	defer span.End()
+
 	httpClient := &http.Client{}
 
-	request, err := http.NewRequest(http.MethodGet, url, nil)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return net.IPNet{}, err
 	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
 
 	response, err := httpClient.Do(request)
 	if err != nil {
@@ -153,6 +144,10 @@ func NebulaClientIP() (net.IPNet, error) {
 		return net.IPNet{}, err
 	}
 
+	if len(gaidysResponse.IpAddresses) == 0 {
+		return net.IPNet{}, fmt.Errorf("no IP addresses assigned to %s", ServerCfg.Login)
+	}
+
 	// Currently return only IPv4
 	return net.IPNet{
 		IP:   net.ParseIP(gaidysResponse.IpAddresses[0]),
@@ -161,11 +156,11 @@ func NebulaClientIP() (net.IPNet, error) {
 }
 
 // NebulaGenerateCertificate generate ca.crt, client.crt, client.key for Nebula
-func NebulaGenerateCertificate(userTeams []string) {
+func NebulaGenerateCertificate(ctx context.Context, userTeams []string) {
 	crtPath := "/etc/nebula/certs/" + ServerCfg.Login + ".crt"
 	keyPath := "/etc/nebula/certs/" + ServerCfg.Login + ".key"
 
-	clientIP, err := NebulaClientIP()
+	clientIP, err := NebulaClientIP(ctx)
 	if err != nil {
 		ServerCfg.Logger.Fatal("failed retrieving client's IP address", zap.Error(err))
 	}
@@ -215,52 +210,3 @@ func NebulaGenerateCertificate(userTeams []string) {
 	ServerCfg.Nebula.Certificate.Crt = string(crt)
 	ServerCfg.Nebula.Certificate.Key = string(key)
 }
-
-// NebulaDownload used to download Nebula binary
-func NebulaDownload() (err error) {
-	err = os.Mkdir(NebulaDir(), 0755)
-	if err != nil {
-		return err
-	}
-
-	out, err := os.Create(NebulaExecutable())
-	if err != nil {
-		Cfg.Logger.Error("can't create Nebula binary",
-			zap.String("Path", NebulaExecutable()),
-			zap.Error(err))
-		return err
-	}
-	defer out.Close()
-
-	err = os.Chmod(NebulaExecutable(), 0755)
-	if err != nil {
-		Cfg.Logger.Error("can't change permissions for Nebula binary",
-			zap.String("Path", NebulaExecutable()),
-			zap.Error(err))
-		return err
-	}
-
-	resp, err := http.Get(nebulaDownloadLink())
-	if err != nil {
-		Cfg.Logger.Error("can't download Nebula binary",
-			zap.String("Url", nebulaDownloadLink()),
-			zap.Error(err))
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed download Nebula: %s", resp.Status)
-	}
-
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		Cfg.Logger.Error("can't write Nebula binary",
-			zap.String("Url", nebulaDownloadLink()),
-			zap.String("Path", NebulaExecutable()),
-			zap.Error(err))
-		return err
-	}
-
-	return nil
-}
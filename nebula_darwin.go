@@ -7,18 +7,19 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"runtime"
 	"strings"
 
 	"go.uber.org/zap"
 )
 
 func nebulaDownloadLink() string {
-	return "https://github.com/hostinger/packages/releases/download/v1.0.0/nebula-1.4.0-darwin-amd64"
+	return "https://github.com/hostinger/packages/releases/download/v1.0.0/nebula-1.4.0-darwin-" + runtime.GOARCH
 }
 
 // NebulaDir absolute paths to the directory of Nebula configurations and binaries
 func NebulaDir() string {
-	return "/opt/nebula"
+	return "/Library/Application Support/Nerf"
 }
 
 // NebulaExecutable show full path of Nebula executable
@@ -128,6 +129,11 @@ func NebulaSetNameServers(e *Endpoint, NameServers []string, save bool) error {
 	return err
 }
 
+// NebulaRevertNameServers reverts name servers to the ones saved by NebulaSetNameServers
+func NebulaRevertNameServers(e *Endpoint) error {
+	return NebulaSetNameServers(e, Cfg.SavedNameServers, false)
+}
+
 // NebulaAddLightHouseStaticRoute add static route towards fastest gRPC server via default route
 func NebulaAddLightHouseStaticRoute(e *Endpoint) error {
 	defaultGw, err := nebulaDefaultGateway(e)
@@ -146,3 +152,8 @@ func NebulaAddLightHouseStaticRoute(e *Endpoint) error {
 
 	return exec.Command("/sbin/route", "-n", "add", "-net", e.RemoteIP, defaultGw).Run()
 }
+
+// NebulaDeleteLightHouseStaticRoute delete static route towards gRPC server
+func NebulaDeleteLightHouseStaticRoute(e *Endpoint) error {
+	return exec.Command("/sbin/route", "-n", "delete", "-net", e.RemoteIP).Run()
+}
@@ -1,23 +1,21 @@
 package nerf
 
 import (
-	"fmt"
 	"net"
-	"os/exec"
 	"path"
-	"strings"
+	"runtime"
 
 	"github.com/vishvananda/netlink"
 	"go.uber.org/zap"
 )
 
 func nebulaDownloadLink() string {
-	return "https://github.com/hostinger/packages/releases/download/v1.0.0/nebula-1.4.0-linux-amd64"
+	return "https://github.com/hostinger/packages/releases/download/v1.0.0/nebula-1.4.0-linux-" + runtime.GOARCH
 }
 
 // NebulaDir absolute paths to the directory of Nebula configurations and binaries
 func NebulaDir() string {
-	return "/opt/nebula"
+	return "/var/lib/nerf"
 }
 
 // NebulaExecutable show full path of Nebula executable
@@ -25,100 +23,76 @@ func NebulaExecutable() string {
 	return path.Join(NebulaDir(), "nebula")
 }
 
-func nebulaGetNameServers() error {
-	var err error
-	var nameServers []string
-
-	cmd := exec.Command(
-		"nmcli",
-		"device",
-		"show",
-	)
-	lines, err := cmd.CombinedOutput()
+// nebulaLink returns the interface towards the endpoint
+func nebulaLink(e *Endpoint) (*net.Interface, error) {
+	routes, err := netlink.RouteGet(net.ParseIP(e.RemoteIP))
 	if err != nil {
-		return fmt.Errorf("failed 'nmcli device show'")
-	}
-
-	for _, param := range strings.Split(string(lines), "\n") {
-		if !strings.Contains(param, "IP4.DNS") {
-			continue
-		}
-
-		ns := strings.TrimSpace(strings.Split(param, ":")[1])
-		if ip := net.ParseIP(ns); ip != nil {
-			nameServers = append(nameServers, ns)
-		}
+		Cfg.Logger.Error("can't get route for gRPC server",
+			zap.String("RemoteIP", e.RemoteIP),
+			zap.Error(err))
+		return nil, err
 	}
 
-	if len(nameServers) == 0 {
-		return fmt.Errorf("failed retrieving current name servers")
+	link, err := net.InterfaceByIndex(routes[0].LinkIndex)
+	if err != nil {
+		Cfg.Logger.Error("can't get default interface by ifIndex",
+			zap.String("Dst", routes[0].Gw.String()),
+			zap.Int("ifIndex", routes[0].LinkIndex),
+			zap.Error(err))
+		return nil, err
 	}
 
-	Cfg.Logger.Debug("saving current name servers", zap.Strings("NameServers", nameServers))
-
-	Cfg.SavedNameServers = nameServers
-
-	return err
+	return link, nil
 }
 
 // NebulaSetNameServers set name server for the client to self
 func NebulaSetNameServers(e *Endpoint, NameServers []string, save bool) error {
-	var err error
-	var dns []string
+	link, err := nebulaLink(e)
+	if err != nil {
+		return err
+	}
 
-	if save {
-		if err = nebulaGetNameServers(); err != nil {
+	if dnsConfigurator == nil {
+		if dnsConfigurator, err = NewDNSConfigurator(Cfg.DNSBackend); err != nil {
 			return err
 		}
 	}
 
-	routes, err := netlink.RouteGet(net.ParseIP(e.RemoteIP))
-	if err != nil {
-		Cfg.Logger.Error("can't get route for gRPC server",
-			zap.String("RemoteIP", e.RemoteIP),
+	if err = dnsConfigurator.Set(link, NameServers, DNSAutoDiscoverZone, save); err != nil {
+		Cfg.Logger.Error("can't set name servers",
+			zap.String("Backend", dnsConfigurator.Name()),
+			zap.String("Interface", link.Name),
+			zap.Strings("NameServers", NameServers),
+			zap.String("Domain", DNSAutoDiscoverZone),
 			zap.Error(err))
 		return err
 	}
 
-	device, err := netlink.LinkByIndex(routes[0].LinkIndex)
-	if err != nil {
-		Cfg.Logger.Error("can't get default interface by ifIndex",
-			zap.String("Dst", routes[0].Gw.String()),
-			zap.Int("ifIndex", routes[0].LinkIndex),
-			zap.Error(err))
-		return err
-	}
+	Cfg.Logger.Debug("setting name servers",
+		zap.String("Backend", dnsConfigurator.Name()),
+		zap.String("Interface", link.Name),
+		zap.Strings("NameServers", NameServers))
 
-	// systemd-resolve expects multiple `--set-dns X.X.X.X --set-dns Y.Y.Y.Y`,
-	// thus crafting a slice for that.
-	for _, ns := range NameServers {
-		dns = append(dns, "--set-dns")
-		dns = append(dns, ns)
+	return nil
+}
+
+// NebulaRevertNameServers reverts name servers changed by NebulaSetNameServers
+func NebulaRevertNameServers(e *Endpoint) error {
+	if dnsConfigurator == nil {
+		return nil
 	}
 
-	cmd := exec.Command(
-		"systemd-resolve",
-	)
-	cmd.Args = append([]string{
-		"systemd-resolve",
-		"--interface",
-		device.Attrs().Name,
-		"--set-domain",
-		DNSAutoDiscoverZone,
-	}, dns...)
-
-	err = cmd.Run()
-	if err != nil {
-		Cfg.Logger.Error("can't set name servers",
-			zap.Strings("NameServers", NameServers),
-			zap.String("Domain", DNSAutoDiscoverZone),
+	if err := dnsConfigurator.Revert(); err != nil {
+		Cfg.Logger.Error("can't revert name servers",
+			zap.String("Backend", dnsConfigurator.Name()),
 			zap.Error(err))
 		return err
 	}
 
-	Cfg.Logger.Debug("setting name servers", zap.Strings("NameServers", NameServers))
+	Cfg.Logger.Debug("reverted name servers", zap.String("Backend", dnsConfigurator.Name()))
+	dnsConfigurator = nil
 
-	return err
+	return nil
 }
 
 // NebulaAddLightHouseStaticRoute add static route towards fastest gRPC server via default route
@@ -150,3 +124,13 @@ func NebulaAddLightHouseStaticRoute(e *Endpoint) error {
 
 	return netlink.RouteAdd(&nr)
 }
+
+// NebulaDeleteLightHouseStaticRoute delete static route towards gRPC server
+func NebulaDeleteLightHouseStaticRoute(e *Endpoint) error {
+	return netlink.RouteDel(&netlink.Route{
+		Dst: &net.IPNet{
+			IP:   net.ParseIP(e.RemoteIP),
+			Mask: net.CIDRMask(32, 32),
+		},
+	})
+}
@@ -2,16 +2,34 @@ package nerf
 
 import (
 	"context"
+	"fmt"
 	math "math"
+	"math/rand"
 	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
+// ServerServiceName is the name nerf-server reports its readiness under
+const ServerServiceName = "nerf.Server"
+
+// DefaultServerPort is used if DNS SRV record does not advertise a port
+const DefaultServerPort = 9000
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
 type NerfMutex struct {
 	sync.Mutex
 	InUse bool
@@ -37,91 +55,551 @@ type Endpoint struct {
 	Description string
 	RemoteHost  string
 	RemoteIP    string
+	RemoteIPs   []string
+	Port        uint16
+	Priority    uint16
+	Weight      uint16
 	Latency     int64
+	Jitter      int64
 }
 
-func probeEndpoint(remoteHost string) int64 {
-	start := time.Now()
+// endpointsMutex guards Cfg.Endpoints and badEndpoints
+var endpointsMutex sync.RWMutex
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// ewmaAlpha is the weight of the latest probe in the smoothed latency
+const ewmaAlpha = 0.3
+
+// latencyEWMA maps RemoteHost of reachable endpoints to their smoothed rank
+var latencyEWMA = map[string]float64{}
+
+// badEndpoints maps RemoteHost of failed endpoints to the time their cool-off ends
+var badEndpoints = map[string]time.Time{}
+
+// MarkEndpointBad excludes the endpoint from selection for Cfg.FailoverCooloff
+func MarkEndpointBad(e *Endpoint) {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+
+	badEndpoints[e.RemoteHost] = time.Now().Add(Cfg.FailoverCooloff)
+}
+
+// endpointBad checks if the endpoint is still cooling off after a failure
+func endpointBad(e *Endpoint) bool {
+	endpointsMutex.RLock()
+	defer endpointsMutex.RUnlock()
+
+	return time.Now().Before(badEndpoints[e.RemoteHost])
+}
+
+// Address returns the address to dial gRPC server
+func (e *Endpoint) Address() string {
+	host := e.RemoteIP
+	if host == "" {
+		host = e.RemoteHost
+	}
+
+	port := e.Port
+	if port == 0 {
+		port = DefaultServerPort
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// probeEndpoint probes all the addresses of the endpoint in parallel
+func probeEndpoint(ctx context.Context, e *Endpoint) {
+	ctx, span := StartSpan(ctx, "probe", EndpointAttribute(e))
+	defer span.End()
+
+	results := make([][]time.Duration, len(e.RemoteIPs))
 
-	conn, err := grpc.DialContext(ctx, remoteHost+":9000", grpc.WithInsecure())
+	var wg sync.WaitGroup
+	for i, ip := range e.RemoteIPs {
+		wg.Add(1)
+		go func(i int, address Endpoint) {
+			defer wg.Done()
+			results[i] = probeAddress(ctx, &address)
+		}(i, Endpoint{RemoteHost: e.RemoteHost, RemoteIP: ip, Port: e.Port})
+	}
+	wg.Wait()
+
+	e.Latency = math.MaxInt64
+	e.Jitter = 0
+
+	for i, samples := range results {
+		if len(samples) == 0 {
+			continue
+		}
+		if latency := medianRTT(samples).Milliseconds(); latency < e.Latency {
+			e.RemoteIP = e.RemoteIPs[i]
+			e.Latency = latency
+			e.Jitter = jitterRTT(samples).Milliseconds()
+		}
+	}
+}
+
+// probeAddress returns RTT samples of Cfg.ProbeSamples pings
+func probeAddress(ctx context.Context, e *Endpoint) []time.Duration {
+	logger := Cfg.Logger.With(TraceFields(ctx)...)
+
+	conn, err := grpc.DialContext(ctx, e.Address(), GRPCDialOptions()...)
 	if err != nil {
-		Cfg.Logger.Error("failed connecting to gRPC",
-			zap.String("RemoteHost", remoteHost),
+		logger.Error("failed connecting to gRPC",
+			zap.String("RemoteHost", e.RemoteHost),
+			zap.String("Address", e.Address()),
 			zap.Error(err))
+		return nil
 	}
 	defer conn.Close()
 
+	if err := endpointReady(ctx, conn); err != nil {
+		logger.Debug("endpoint is not ready yet",
+			zap.String("RemoteHost", e.RemoteHost),
+			zap.String("Address", e.Address()),
+			zap.Error(err))
+		return nil
+	}
+
 	client := NewServerClient(conn)
-	data := start.UnixNano()
-	request := &PingRequest{Data: data, Login: Cfg.Login}
-	response, err := client.Ping(ctx, request)
-	if err != nil || response.Data == 0 {
-		return math.MaxInt64
+	samples := make([]time.Duration, 0, Cfg.ProbeSamples)
+
+	for i := 0; i < Cfg.ProbeSamples; i++ {
+		start := time.Now()
+		request := &PingRequest{Data: start.UnixNano(), Login: Cfg.State.Session().Login}
+		response, err := client.Ping(ctx, request)
+		if err != nil || response.Data == 0 {
+			logger.Debug("failed ping request",
+				zap.String("RemoteHost", e.RemoteHost),
+				zap.String("Address", e.Address()),
+				zap.Error(err))
+			break
+		}
+		samples = append(samples, time.Since(start))
 	}
 
-	return time.Since(start).Milliseconds()
+	return samples
 }
 
-func getVPNEndpoints() {
-	r := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: 3 * time.Second,
+// medianRTT returns the median of RTT samples
+func medianRTT(samples []time.Duration) time.Duration {
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+// jitterRTT returns the mean difference between consecutive RTT samples
+func jitterRTT(samples []time.Duration) time.Duration {
+	if len(samples) < 2 {
+		return 0
+	}
+
+	var total time.Duration
+	for i := 1; i < len(samples); i++ {
+		diff := samples[i] - samples[i-1]
+		if diff < 0 {
+			diff = -diff
+		}
+		total += diff
+	}
+
+	return total / time.Duration(len(samples)-1)
+}
+
+// endpointReady checks if nerf-server reports SERVING, older servers are always ready
+func endpointReady(ctx context.Context, conn *grpc.ClientConn) error {
+	response, err := healthpb.NewHealthClient(conn).Check(
+		ctx,
+		&healthpb.HealthCheckRequest{Service: ServerServiceName},
+	)
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return err
+	}
+
+	if response.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health status is %s", response.Status)
+	}
+
+	return nil
+}
+
+// ParseStaticEndpoints parses endpoints specified as `host[:port][=description]`
+func ParseStaticEndpoints(specs []string) ([]Endpoint, error) {
+	var endpoints []Endpoint
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		address := spec
+		description := ""
+		if i := strings.Index(spec, "="); i >= 0 {
+			address, description = spec[:i], spec[i+1:]
+		}
+
+		host := address
+		port := uint64(DefaultServerPort)
+		if h, p, err := net.SplitHostPort(address); err == nil {
+			host = h
+			port, err = strconv.ParseUint(p, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port of static endpoint: %s", spec)
 			}
-			return d.DialContext(ctx, "udp", "1.1.1.1:53")
-		},
+		}
+		if host == "" {
+			return nil, fmt.Errorf("invalid static endpoint: %s", spec)
+		}
+		if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			return nil, fmt.Errorf("IPv6 static endpoint is not supported: %s", spec)
+		}
+		if description == "" {
+			description = host
+		}
+
+		endpoints = append(endpoints, Endpoint{
+			Description: description,
+			RemoteHost:  host,
+			Port:        uint16(port),
+		})
+	}
+
+	return endpoints, nil
+}
+
+// staticEndpoints resolves addresses of Cfg.StaticEndpoints
+func staticEndpoints(ctx context.Context) (map[string]Endpoint, error) {
+	endpoints := map[string]Endpoint{}
+
+	for _, endpoint := range Cfg.StaticEndpoints {
+		if ip := net.ParseIP(endpoint.RemoteHost); ip != nil {
+			endpoint.RemoteIPs = []string{ip.String()}
+		} else {
+			addrs, err := Cfg.Resolver.LookupHost(ctx, endpoint.RemoteHost)
+			addrs = ipv4Addresses(addrs)
+			if err != nil || len(addrs) == 0 {
+				return nil, fmt.Errorf("can't resolve static endpoint %s: %v", endpoint.RemoteHost, err)
+			}
+			endpoint.RemoteIPs = addrs
+		}
+		endpoint.RemoteIP = endpoint.RemoteIPs[0]
+		endpoints[endpoint.RemoteHost] = endpoint
+	}
+
+	return endpoints, nil
+}
+
+// ipv4Addresses filters out IPv6 addresses, routes to endpoints are IPv4 only
+func ipv4Addresses(addrs []string) []string {
+	var ipv4 []string
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+			ipv4 = append(ipv4, addr)
+		}
 	}
 
-	_, srvRecords, err := r.LookupSRV(context.Background(), "vpn", "udp", DNSAutoDiscoverZone)
+	return ipv4
+}
+
+// dnsEndpoints discovers endpoints via DNS SRV records, and returns the lowest TTL
+func dnsEndpoints(ctx context.Context) (map[string]Endpoint, time.Duration, error) {
+	r := Cfg.Resolver
+	ctx, recorder := withTTLRecorder(ctx)
+
+	_, srvRecords, err := r.LookupSRV(ctx, "vpn", "udp", DNSAutoDiscoverZone)
 	if err != nil {
-		Cfg.Logger.Fatal("no available gRPC endpoints found (DNS SRV)", zap.Error(err))
+		return nil, 0, fmt.Errorf("no available gRPC endpoints found (DNS SRV): %s", err)
 	}
 
+	endpoints := map[string]Endpoint{}
+
 	for _, record := range srvRecords {
-		txtRecords, err := r.LookupTXT(context.Background(), record.Target)
+		txtRecords, err := r.LookupTXT(ctx, record.Target)
 		if err != nil || len(txtRecords) == 0 {
-			Cfg.Logger.Fatal("no available endpoint's data found (DNS TXT)", zap.Error(err))
+			return nil, 0, fmt.Errorf("no available endpoint's data found (DNS TXT) for %s: %v", record.Target, err)
 		}
-		aRecords, err := r.LookupHost(context.Background(), record.Target)
+		aRecords, err := r.LookupHost(ctx, record.Target)
+		aRecords = ipv4Addresses(aRecords)
 		if err != nil || len(aRecords) == 0 {
-			Cfg.Logger.Fatal("no available endpoint's data found (DNS A)", zap.Error(err))
+			return nil, 0, fmt.Errorf("no available endpoint's data found (DNS A) for %s: %v", record.Target, err)
 		}
 		endpoint := Endpoint{
 			Description: txtRecords[0],
 			RemoteHost:  record.Target,
 			RemoteIP:    aRecords[0],
-			Latency:     probeEndpoint(record.Target),
+			RemoteIPs:   aRecords,
+			Port:        record.Port,
+			Priority:    record.Priority,
+			Weight:      record.Weight,
 		}
-		Cfg.Endpoints[record.Target] = endpoint
+		endpoints[record.Target] = endpoint
+	}
+
+	ttl, ok := recorder.TTL()
+	if !ok {
+		ttl = Cfg.EndpointsCacheTTL
 	}
+
+	return endpoints, ttl, nil
 }
 
-// GetFastestEndpoint returns fastest gRPC endpoint
-func GetFastestEndpoint() Endpoint {
-	getVPNEndpoints()
+// getVPNEndpoints discovers endpoints and probes them
+func getVPNEndpoints(ctx context.Context) error {
+	ctx, span := StartSpan(ctx, "discover")
+	defer span.End()
+
+	logger := Cfg.Logger.With(TraceFields(ctx)...)
+
+	var endpoints map[string]Endpoint
+	var ttl time.Duration
+	var err error
 
-	var fastestEndpoint Endpoint
-	var latency int64 = math.MaxInt64
+	cacheable := false
+	if len(Cfg.StaticEndpoints) > 0 {
+		endpoints, err = staticEndpoints(ctx)
+	} else {
+		endpoints, ttl, err = dnsEndpoints(ctx)
+		cacheable = err == nil
+		if err != nil {
+			if cached, cacheErr := loadEndpointsCache(Cfg.EndpointsCacheGrace); cacheErr == nil {
+				logger.Warn("endpoint discovery failed, using cached endpoints",
+					zap.Time("ExpiredAt", cached.ExpiresAt),
+					zap.Error(err))
+				endpoints, err = cached.endpoints(), nil
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
 
+	// Probe all the endpoints in parallel, so a single unreachable endpoint
+	// delays the discovery no longer than Cfg.ProbeTimeout.
+	ctx, cancel := context.WithTimeout(ctx, Cfg.ProbeTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	probed := make(map[string]Endpoint, len(endpoints))
+
+	for target, endpoint := range endpoints {
+		wg.Add(1)
+		go func(target string, endpoint Endpoint) {
+			defer wg.Done()
+			probeEndpoint(ctx, &endpoint)
+			mutex.Lock()
+			probed[target] = endpoint
+			mutex.Unlock()
+		}(target, endpoint)
+	}
+	wg.Wait()
+	endpoints = probed
+
+	endpointsMutex.Lock()
+	Cfg.Endpoints = endpoints
+	updateLatencyEWMA(endpoints)
+	endpointsMutex.Unlock()
+
+	if cacheable {
+		if err := saveEndpointsCache(endpoints, ttl); err != nil {
+			logger.Warn("can't cache endpoints", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// updateLatencyEWMA smooths the rank, must be called with endpointsMutex locked
+func updateLatencyEWMA(endpoints map[string]Endpoint) {
+	for host, e := range endpoints {
+		if e.rank() == math.MaxInt64 {
+			delete(latencyEWMA, host)
+			continue
+		}
+		previous, ok := latencyEWMA[host]
+		if !ok {
+			latencyEWMA[host] = float64(e.rank())
+			continue
+		}
+		latencyEWMA[host] = ewmaAlpha*float64(e.rank()) + (1-ewmaAlpha)*previous
+	}
+}
+
+// smoothedLatency returns the EWMA of the endpoint's rank
+func smoothedLatency(e *Endpoint) (float64, bool) {
+	endpointsMutex.RLock()
+	defer endpointsMutex.RUnlock()
+
+	latency, ok := latencyEWMA[e.RemoteHost]
+	return latency, ok
+}
+
+// rank returns the value endpoints are compared by
+func (e *Endpoint) rank() int64 {
+	if e.Latency == math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return e.Latency + e.Jitter
+}
+
+// ProbedEndpoints returns the endpoints sorted by priority and RTT
+func ProbedEndpoints() []Endpoint {
+	endpointsMutex.RLock()
+	defer endpointsMutex.RUnlock()
+
+	endpoints := make([]Endpoint, 0, len(Cfg.Endpoints))
 	for _, e := range Cfg.Endpoints {
-		if e.Latency < latency {
-			fastestEndpoint = e
-			latency = e.Latency
+		endpoints = append(endpoints, e)
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Priority != endpoints[j].Priority {
+			return endpoints[i].Priority < endpoints[j].Priority
+		}
+		if endpoints[i].rank() != endpoints[j].rank() {
+			return endpoints[i].rank() < endpoints[j].rank()
+		}
+		return endpoints[i].RemoteHost < endpoints[j].RemoteHost
+	})
+
+	return endpoints
+}
+
+// endpointPreferred checks if the endpoint matches the user's preference
+func endpointPreferred(e *Endpoint) bool {
+	if Cfg.PreferredEndpoint != "" {
+		preferred := strings.TrimSuffix(Cfg.PreferredEndpoint, ".")
+		if strings.TrimSuffix(e.RemoteHost, ".") != preferred {
+			found := false
+			for _, ip := range e.RemoteIPs {
+				found = found || ip == preferred
+			}
+			if !found {
+				return false
+			}
 		}
-		Cfg.Logger.Debug(
-			"probing endpoint",
+	}
+
+	if Cfg.PreferredRegion != "" {
+		return strings.Contains(
+			strings.ToLower(e.Description),
+			strings.ToLower(Cfg.PreferredRegion),
+		)
+	}
+
+	return true
+}
+
+// SelectEndpoint returns the best gRPC endpoint according to RFC 2782
+func SelectEndpoint(ctx context.Context) (Endpoint, error) {
+	logger := Cfg.Logger.With(TraceFields(ctx)...)
+
+	if useCachedEndpoints(ctx) {
+		// Refresh the cache for the next time, while connecting via cached endpoints.
+		// The refresh outlives connecting, which may be cancelled or finish first.
+		go func() {
+			refreshCtx, cancel := context.WithTimeout(WithRequestID(context.Background()), time.Minute)
+			defer cancel()
+
+			if err := getVPNEndpoints(refreshCtx); err != nil {
+				logger.Warn("can't refresh cached endpoints", zap.Error(err))
+			}
+		}()
+	} else if err := getVPNEndpoints(ctx); err != nil {
+		return Endpoint{}, err
+	}
+
+	var group, badGroup []Endpoint
+
+	for _, e := range ProbedEndpoints() {
+		logger.Debug("probing endpoint",
 			zap.String("RemoteIP", e.RemoteIP),
 			zap.String("RemoteHost", e.RemoteHost),
 			zap.String("Description", e.Description),
+			zap.Uint16("Priority", e.Priority),
+			zap.Uint16("Weight", e.Weight),
 			zap.Int64("Latency (ms)", e.Latency),
-		)
+			zap.Int64("Jitter (ms)", e.Jitter))
+
+		if e.Latency == math.MaxInt64 || !endpointPreferred(&e) {
+			continue
+		}
+		if endpointBad(&e) {
+			badGroup = appendToPriorityGroup(badGroup, e)
+			continue
+		}
+		group = appendToPriorityGroup(group, e)
+	}
+
+	if len(group) == 0 {
+		return selectWeightedEndpoint(badGroup), nil
+	}
+
+	return selectWeightedEndpoint(group), nil
+}
+
+// appendToPriorityGroup keeps only the endpoints of the lowest priority in the group
+func appendToPriorityGroup(group []Endpoint, e Endpoint) []Endpoint {
+	if len(group) == 0 || e.Priority < group[0].Priority {
+		return []Endpoint{e}
+	}
+	if e.Priority == group[0].Priority {
+		return append(group, e)
+	}
+
+	return group
+}
+
+// selectWeightedEndpoint selects an endpoint randomly proportionally to its weight
+func selectWeightedEndpoint(group []Endpoint) Endpoint {
+	if len(group) == 0 {
+		return Endpoint{}
+	}
+
+	// Keep the order stable for the same set of endpoints.
+	sort.Slice(group, func(i, j int) bool {
+		return group[i].RemoteHost < group[j].RemoteHost
+	})
+
+	var fastest int64 = math.MaxInt64
+	for _, e := range group {
+		if e.rank() < fastest {
+			fastest = e.rank()
+		}
+	}
+
+	// Endpoints with zero weight have a very small chance to be selected,
+	// unless all of them have zero weight (RFC 2782).
+	weights := make([]float64, len(group))
+	var total float64
+	for i, e := range group {
+		weights[i] = float64(e.Weight) + 0.01
+		weights[i] *= float64(fastest+1) / float64(e.rank()+1)
+		total += weights[i]
+	}
+
+	pick := rand.Float64() * total
+	for i, e := range group {
+		if pick < weights[i] {
+			return e
+		}
+		pick -= weights[i]
 	}
 
-	return fastestEndpoint
+	return group[len(group)-1]
 }
 
 // StringToLogLevel convert loglevel string into zapCore.Level enum
@@ -1,309 +1,2150 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.12.4
 // source: nerf.proto
 
-/*
-Package nerf is a generated protocol buffer package.
+package nerf
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Event_Type int32
+
+const (
+	Event_UNKNOWN               Event_Type = 0
+	Event_TEAMS_CHANGED         Event_Type = 1
+	Event_CERTIFICATE_REVOKED   Event_Type = 2
+	Event_FORCED_DISCONNECT     Event_Type = 3
+	Event_MAINTENANCE_SCHEDULED Event_Type = 4
+	Event_CONFIG_AVAILABLE      Event_Type = 5
+	Event_ENDPOINT_SWITCHED     Event_Type = 6
+	Event_NEBULA_RESTARTED      Event_Type = 7
+)
+
+// Enum value maps for Event_Type.
+var (
+	Event_Type_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "TEAMS_CHANGED",
+		2: "CERTIFICATE_REVOKED",
+		3: "FORCED_DISCONNECT",
+		4: "MAINTENANCE_SCHEDULED",
+		5: "CONFIG_AVAILABLE",
+		6: "ENDPOINT_SWITCHED",
+		7: "NEBULA_RESTARTED",
+	}
+	Event_Type_value = map[string]int32{
+		"UNKNOWN":               0,
+		"TEAMS_CHANGED":         1,
+		"CERTIFICATE_REVOKED":   2,
+		"FORCED_DISCONNECT":     3,
+		"MAINTENANCE_SCHEDULED": 4,
+		"CONFIG_AVAILABLE":      5,
+		"ENDPOINT_SWITCHED":     6,
+		"NEBULA_RESTARTED":      7,
+	}
+)
+
+func (x Event_Type) Enum() *Event_Type {
+	p := new(Event_Type)
+	*p = x
+	return p
+}
+
+func (x Event_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Event_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_nerf_proto_enumTypes[0].Descriptor()
+}
+
+func (Event_Type) Type() protoreflect.EnumType {
+	return &file_nerf_proto_enumTypes[0]
+}
+
+func (x Event_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Event_Type.Descriptor instead.
+func (Event_Type) EnumDescriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{8, 0}
+}
+
+type Status_State int32
+
+const (
+	Status_DISCONNECTED    Status_State = 0
+	Status_DISCOVERING     Status_State = 1
+	Status_AUTHENTICATING  Status_State = 2
+	Status_CONFIGURING     Status_State = 3
+	Status_STARTING_NEBULA Status_State = 4
+	Status_CONNECTED       Status_State = 5
+	Status_RECONNECTING    Status_State = 6
+	Status_DISCONNECTING   Status_State = 7
+)
+
+// Enum value maps for Status_State.
+var (
+	Status_State_name = map[int32]string{
+		0: "DISCONNECTED",
+		1: "DISCOVERING",
+		2: "AUTHENTICATING",
+		3: "CONFIGURING",
+		4: "STARTING_NEBULA",
+		5: "CONNECTED",
+		6: "RECONNECTING",
+		7: "DISCONNECTING",
+	}
+	Status_State_value = map[string]int32{
+		"DISCONNECTED":    0,
+		"DISCOVERING":     1,
+		"AUTHENTICATING":  2,
+		"CONFIGURING":     3,
+		"STARTING_NEBULA": 4,
+		"CONNECTED":       5,
+		"RECONNECTING":    6,
+		"DISCONNECTING":   7,
+	}
+)
+
+func (x Status_State) Enum() *Status_State {
+	p := new(Status_State)
+	*p = x
+	return p
+}
+
+func (x Status_State) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Status_State) Descriptor() protoreflect.EnumDescriptor {
+	return file_nerf_proto_enumTypes[1].Descriptor()
+}
+
+func (Status_State) Type() protoreflect.EnumType {
+	return &file_nerf_proto_enumTypes[1]
+}
+
+func (x Status_State) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Status_State.Descriptor instead.
+func (Status_State) EnumDescriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{11, 0}
+}
+
+type PingRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data  int64  `protobuf:"varint,1,opt,name=data,proto3" json:"data,omitempty"`
+	Login string `protobuf:"bytes,2,opt,name=login,proto3" json:"login,omitempty"`
+}
+
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingRequest) ProtoMessage() {}
+
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PingRequest) GetData() int64 {
+	if x != nil {
+		return x.Data
+	}
+	return 0
+}
+
+func (x *PingRequest) GetLogin() string {
+	if x != nil {
+		return x.Login
+	}
+	return ""
+}
+
+type PingResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data int64 `protobuf:"varint,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *PingResponse) Reset() {
+	*x = PingResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResponse) ProtoMessage() {}
+
+func (x *PingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PingResponse) GetData() int64 {
+	if x != nil {
+		return x.Data
+	}
+	return 0
+}
+
+type Request struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Login    string `protobuf:"bytes,1,opt,name=login,proto3" json:"login,omitempty"`
+	Token    string `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	Endpoint string `protobuf:"bytes,3,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Region   string `protobuf:"bytes,4,opt,name=region,proto3" json:"region,omitempty"`
+}
+
+func (x *Request) Reset() {
+	*x = Request{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request) ProtoMessage() {}
+
+func (x *Request) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request.ProtoReflect.Descriptor instead.
+func (*Request) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Request) GetLogin() string {
+	if x != nil {
+		return x.Login
+	}
+	return ""
+}
+
+func (x *Request) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *Request) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *Request) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+type Response struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Config         string          `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	ClientIP       string          `protobuf:"bytes,2,opt,name=clientIP,proto3" json:"clientIP,omitempty"`
+	Teams          []string        `protobuf:"bytes,3,rep,name=teams,proto3" json:"teams,omitempty"`
+	LightHouseIP   string          `protobuf:"bytes,4,opt,name=lightHouseIP,proto3" json:"lightHouseIP,omitempty"`
+	NebulaManifest *NebulaManifest `protobuf:"bytes,5,opt,name=nebulaManifest,proto3" json:"nebulaManifest,omitempty"`
+}
+
+func (x *Response) Reset() {
+	*x = Response{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response) ProtoMessage() {}
+
+func (x *Response) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response.ProtoReflect.Descriptor instead.
+func (*Response) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Response) GetConfig() string {
+	if x != nil {
+		return x.Config
+	}
+	return ""
+}
+
+func (x *Response) GetClientIP() string {
+	if x != nil {
+		return x.ClientIP
+	}
+	return ""
+}
+
+func (x *Response) GetTeams() []string {
+	if x != nil {
+		return x.Teams
+	}
+	return nil
+}
+
+func (x *Response) GetLightHouseIP() string {
+	if x != nil {
+		return x.LightHouseIP
+	}
+	return ""
+}
+
+func (x *Response) GetNebulaManifest() *NebulaManifest {
+	if x != nil {
+		return x.NebulaManifest
+	}
+	return nil
+}
+
+type NebulaRelease struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version string `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Os      string `protobuf:"bytes,2,opt,name=os,proto3" json:"os,omitempty"`
+	Arch    string `protobuf:"bytes,3,opt,name=arch,proto3" json:"arch,omitempty"`
+	Url     string `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	Sha256  string `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`
+}
+
+func (x *NebulaRelease) Reset() {
+	*x = NebulaRelease{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NebulaRelease) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NebulaRelease) ProtoMessage() {}
+
+func (x *NebulaRelease) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NebulaRelease.ProtoReflect.Descriptor instead.
+func (*NebulaRelease) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *NebulaRelease) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *NebulaRelease) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *NebulaRelease) GetArch() string {
+	if x != nil {
+		return x.Arch
+	}
+	return ""
+}
+
+func (x *NebulaRelease) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *NebulaRelease) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type NebulaManifest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MinVersion string           `protobuf:"bytes,1,opt,name=minVersion,proto3" json:"minVersion,omitempty"`
+	Releases   []*NebulaRelease `protobuf:"bytes,2,rep,name=releases,proto3" json:"releases,omitempty"`
+}
+
+func (x *NebulaManifest) Reset() {
+	*x = NebulaManifest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NebulaManifest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NebulaManifest) ProtoMessage() {}
+
+func (x *NebulaManifest) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NebulaManifest.ProtoReflect.Descriptor instead.
+func (*NebulaManifest) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *NebulaManifest) GetMinVersion() string {
+	if x != nil {
+		return x.MinVersion
+	}
+	return ""
+}
+
+func (x *NebulaManifest) GetReleases() []*NebulaRelease {
+	if x != nil {
+		return x.Releases
+	}
+	return nil
+}
+
+type ApiResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ClientIP string `protobuf:"bytes,1,opt,name=clientIP,proto3" json:"clientIP,omitempty"`
+	RemoteIP string `protobuf:"bytes,2,opt,name=remoteIP,proto3" json:"remoteIP,omitempty"`
+}
+
+func (x *ApiResponse) Reset() {
+	*x = ApiResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ApiResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApiResponse) ProtoMessage() {}
+
+func (x *ApiResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApiResponse.ProtoReflect.Descriptor instead.
+func (*ApiResponse) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ApiResponse) GetClientIP() string {
+	if x != nil {
+		return x.ClientIP
+	}
+	return ""
+}
+
+func (x *ApiResponse) GetRemoteIP() string {
+	if x != nil {
+		return x.RemoteIP
+	}
+	return ""
+}
+
+type Notify struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Login string `protobuf:"bytes,1,opt,name=login,proto3" json:"login,omitempty"`
+}
+
+func (x *Notify) Reset() {
+	*x = Notify{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Notify) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Notify) ProtoMessage() {}
+
+func (x *Notify) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Notify.ProtoReflect.Descriptor instead.
+func (*Notify) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Notify) GetLogin() string {
+	if x != nil {
+		return x.Login
+	}
+	return ""
+}
+
+type Event struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type      Event_Type `protobuf:"varint,1,opt,name=type,proto3,enum=nerf.Event_Type" json:"type,omitempty"`
+	Message   string     `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp int64      `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	RemoteIP  string     `protobuf:"bytes,4,opt,name=remoteIP,proto3" json:"remoteIP,omitempty"`
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Event) GetType() Event_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Event_UNKNOWN
+}
+
+func (x *Event) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Event) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Event) GetRemoteIP() string {
+	if x != nil {
+		return x.RemoteIP
+	}
+	return ""
+}
+
+type EndpointInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Description string `protobuf:"bytes,1,opt,name=description,proto3" json:"description,omitempty"`
+	RemoteHost  string `protobuf:"bytes,2,opt,name=remoteHost,proto3" json:"remoteHost,omitempty"`
+	RemoteIP    string `protobuf:"bytes,3,opt,name=remoteIP,proto3" json:"remoteIP,omitempty"`
+	Port        uint32 `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Priority    uint32 `protobuf:"varint,5,opt,name=priority,proto3" json:"priority,omitempty"`
+	Weight      uint32 `protobuf:"varint,6,opt,name=weight,proto3" json:"weight,omitempty"`
+	Latency     int64  `protobuf:"varint,7,opt,name=latency,proto3" json:"latency,omitempty"`
+	Jitter      int64  `protobuf:"varint,8,opt,name=jitter,proto3" json:"jitter,omitempty"`
+	Reachable   bool   `protobuf:"varint,9,opt,name=reachable,proto3" json:"reachable,omitempty"`
+	Current     bool   `protobuf:"varint,10,opt,name=current,proto3" json:"current,omitempty"`
+}
+
+func (x *EndpointInfo) Reset() {
+	*x = EndpointInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EndpointInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndpointInfo) ProtoMessage() {}
+
+func (x *EndpointInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndpointInfo.ProtoReflect.Descriptor instead.
+func (*EndpointInfo) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *EndpointInfo) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *EndpointInfo) GetRemoteHost() string {
+	if x != nil {
+		return x.RemoteHost
+	}
+	return ""
+}
+
+func (x *EndpointInfo) GetRemoteIP() string {
+	if x != nil {
+		return x.RemoteIP
+	}
+	return ""
+}
+
+func (x *EndpointInfo) GetPort() uint32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetPriority() uint32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetWeight() uint32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetLatency() int64 {
+	if x != nil {
+		return x.Latency
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetJitter() int64 {
+	if x != nil {
+		return x.Jitter
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetReachable() bool {
+	if x != nil {
+		return x.Reachable
+	}
+	return false
+}
+
+func (x *EndpointInfo) GetCurrent() bool {
+	if x != nil {
+		return x.Current
+	}
+	return false
+}
+
+type EndpointsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Endpoints []*EndpointInfo `protobuf:"bytes,1,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
+}
+
+func (x *EndpointsResponse) Reset() {
+	*x = EndpointsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EndpointsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
-It is generated from these files:
-	nerf.proto
+func (*EndpointsResponse) ProtoMessage() {}
 
-It has these top-level messages:
-	PingRequest
-	PingResponse
-	Request
-	Response
-	ApiResponse
-	Notify
-*/
-package nerf
+func (x *EndpointsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
 
-import proto "github.com/golang/protobuf/proto"
-import fmt "fmt"
-import math "math"
-import google_protobuf "github.com/golang/protobuf/ptypes/empty"
+// Deprecated: Use EndpointsResponse.ProtoReflect.Descriptor instead.
+func (*EndpointsResponse) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{10}
+}
 
-import (
-	context "context"
-	grpc "google.golang.org/grpc"
-)
+func (x *EndpointsResponse) GetEndpoints() []*EndpointInfo {
+	if x != nil {
+		return x.Endpoints
+	}
+	return nil
+}
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ = proto.Marshal
-var _ = fmt.Errorf
-var _ = math.Inf
+type Status struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	State       Status_State `protobuf:"varint,1,opt,name=state,proto3,enum=nerf.Status_State" json:"state,omitempty"`
+	Since       int64        `protobuf:"varint,2,opt,name=since,proto3" json:"since,omitempty"`
+	RemoteHost  string       `protobuf:"bytes,3,opt,name=remoteHost,proto3" json:"remoteHost,omitempty"`
+	Description string       `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	RemoteIP    string       `protobuf:"bytes,5,opt,name=remoteIP,proto3" json:"remoteIP,omitempty"`
+	ClientIP    string       `protobuf:"bytes,6,opt,name=clientIP,proto3" json:"clientIP,omitempty"`
+	Teams       []string     `protobuf:"bytes,7,rep,name=teams,proto3" json:"teams,omitempty"`
+	Error       string       `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	ErrorReason string       `protobuf:"bytes,9,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the proto package it is being compiled against.
-// A compilation error at this line likely means your copy of the
-// proto package needs to be updated.
-const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+func (x *Status) Reset() {
+	*x = Status{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
 
-type PingRequest struct {
-	Data  int64  `protobuf:"varint,1,opt,name=data" json:"data,omitempty"`
-	Login string `protobuf:"bytes,2,opt,name=login" json:"login,omitempty"`
+func (x *Status) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Status) ProtoMessage() {}
+
+func (x *Status) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Status.ProtoReflect.Descriptor instead.
+func (*Status) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{11}
 }
 
-func (m *PingRequest) Reset()                    { *m = PingRequest{} }
-func (m *PingRequest) String() string            { return proto.CompactTextString(m) }
-func (*PingRequest) ProtoMessage()               {}
-func (*PingRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (x *Status) GetState() Status_State {
+	if x != nil {
+		return x.State
+	}
+	return Status_DISCONNECTED
+}
 
-func (m *PingRequest) GetData() int64 {
-	if m != nil {
-		return m.Data
+func (x *Status) GetSince() int64 {
+	if x != nil {
+		return x.Since
 	}
 	return 0
 }
 
-func (m *PingRequest) GetLogin() string {
-	if m != nil {
-		return m.Login
+func (x *Status) GetRemoteHost() string {
+	if x != nil {
+		return x.RemoteHost
 	}
 	return ""
 }
 
-type PingResponse struct {
-	Data int64 `protobuf:"varint,1,opt,name=data" json:"data,omitempty"`
+func (x *Status) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
 }
 
-func (m *PingResponse) Reset()                    { *m = PingResponse{} }
-func (m *PingResponse) String() string            { return proto.CompactTextString(m) }
-func (*PingResponse) ProtoMessage()               {}
-func (*PingResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
-
-func (m *PingResponse) GetData() int64 {
-	if m != nil {
-		return m.Data
+func (x *Status) GetRemoteIP() string {
+	if x != nil {
+		return x.RemoteIP
 	}
-	return 0
+	return ""
 }
 
-type Request struct {
-	Login string `protobuf:"bytes,1,opt,name=login" json:"login,omitempty"`
-	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+func (x *Status) GetClientIP() string {
+	if x != nil {
+		return x.ClientIP
+	}
+	return ""
 }
 
-func (m *Request) Reset()                    { *m = Request{} }
-func (m *Request) String() string            { return proto.CompactTextString(m) }
-func (*Request) ProtoMessage()               {}
-func (*Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (x *Status) GetTeams() []string {
+	if x != nil {
+		return x.Teams
+	}
+	return nil
+}
 
-func (m *Request) GetLogin() string {
-	if m != nil {
-		return m.Login
+func (x *Status) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
 
-func (m *Request) GetToken() string {
-	if m != nil {
-		return m.Token
+func (x *Status) GetErrorReason() string {
+	if x != nil {
+		return x.ErrorReason
 	}
 	return ""
 }
 
-type Response struct {
-	Config       string   `protobuf:"bytes,1,opt,name=config" json:"config,omitempty"`
-	ClientIP     string   `protobuf:"bytes,2,opt,name=clientIP" json:"clientIP,omitempty"`
-	Teams        []string `protobuf:"bytes,3,rep,name=teams" json:"teams,omitempty"`
-	LightHouseIP string   `protobuf:"bytes,4,opt,name=lightHouseIP" json:"lightHouseIP,omitempty"`
+type HelperRoute struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RemoteIP string `protobuf:"bytes,1,opt,name=remoteIP,proto3" json:"remoteIP,omitempty"`
+}
+
+func (x *HelperRoute) Reset() {
+	*x = HelperRoute{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HelperRoute) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HelperRoute) ProtoMessage() {}
+
+func (x *HelperRoute) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func (m *Response) Reset()                    { *m = Response{} }
-func (m *Response) String() string            { return proto.CompactTextString(m) }
-func (*Response) ProtoMessage()               {}
-func (*Response) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+// Deprecated: Use HelperRoute.ProtoReflect.Descriptor instead.
+func (*HelperRoute) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{12}
+}
 
-func (m *Response) GetConfig() string {
-	if m != nil {
-		return m.Config
+func (x *HelperRoute) GetRemoteIP() string {
+	if x != nil {
+		return x.RemoteIP
 	}
 	return ""
 }
 
-func (m *Response) GetClientIP() string {
-	if m != nil {
-		return m.ClientIP
+type HelperNameServers struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RemoteIP    string   `protobuf:"bytes,1,opt,name=remoteIP,proto3" json:"remoteIP,omitempty"`
+	NameServers []string `protobuf:"bytes,2,rep,name=nameServers,proto3" json:"nameServers,omitempty"`
+	Save        bool     `protobuf:"varint,3,opt,name=save,proto3" json:"save,omitempty"`
+}
+
+func (x *HelperNameServers) Reset() {
+	*x = HelperNameServers{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HelperNameServers) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HelperNameServers) ProtoMessage() {}
+
+func (x *HelperNameServers) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HelperNameServers.ProtoReflect.Descriptor instead.
+func (*HelperNameServers) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HelperNameServers) GetRemoteIP() string {
+	if x != nil {
+		return x.RemoteIP
 	}
 	return ""
 }
 
-func (m *Response) GetTeams() []string {
-	if m != nil {
-		return m.Teams
+func (x *HelperNameServers) GetNameServers() []string {
+	if x != nil {
+		return x.NameServers
 	}
 	return nil
 }
 
-func (m *Response) GetLightHouseIP() string {
-	if m != nil {
-		return m.LightHouseIP
+func (x *HelperNameServers) GetSave() bool {
+	if x != nil {
+		return x.Save
 	}
-	return ""
+	return false
 }
 
-type ApiResponse struct {
-	ClientIP string `protobuf:"bytes,1,opt,name=clientIP" json:"clientIP,omitempty"`
-	RemoteIP string `protobuf:"bytes,2,opt,name=remoteIP" json:"remoteIP,omitempty"`
+type HelperNebula struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Config string `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+}
+
+func (x *HelperNebula) Reset() {
+	*x = HelperNebula{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HelperNebula) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (m *ApiResponse) Reset()                    { *m = ApiResponse{} }
-func (m *ApiResponse) String() string            { return proto.CompactTextString(m) }
-func (*ApiResponse) ProtoMessage()               {}
-func (*ApiResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*HelperNebula) ProtoMessage() {}
 
-func (m *ApiResponse) GetClientIP() string {
-	if m != nil {
-		return m.ClientIP
+func (x *HelperNebula) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HelperNebula.ProtoReflect.Descriptor instead.
+func (*HelperNebula) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *HelperNebula) GetConfig() string {
+	if x != nil {
+		return x.Config
 	}
 	return ""
 }
 
-func (m *ApiResponse) GetRemoteIP() string {
-	if m != nil {
-		return m.RemoteIP
+type HelperNebulaOutput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pid    int32  `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	Stream string `protobuf:"bytes,2,opt,name=stream,proto3" json:"stream,omitempty"`
+	Line   string `protobuf:"bytes,3,opt,name=line,proto3" json:"line,omitempty"`
+	Exited bool   `protobuf:"varint,4,opt,name=exited,proto3" json:"exited,omitempty"`
+	Error  string `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *HelperNebulaOutput) Reset() {
+	*x = HelperNebulaOutput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HelperNebulaOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HelperNebulaOutput) ProtoMessage() {}
+
+func (x *HelperNebulaOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HelperNebulaOutput.ProtoReflect.Descriptor instead.
+func (*HelperNebulaOutput) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *HelperNebulaOutput) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *HelperNebulaOutput) GetStream() string {
+	if x != nil {
+		return x.Stream
 	}
 	return ""
 }
 
-type Notify struct {
-	Login string `protobuf:"bytes,1,opt,name=login" json:"login,omitempty"`
+func (x *HelperNebulaOutput) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
 }
 
-func (m *Notify) Reset()                    { *m = Notify{} }
-func (m *Notify) String() string            { return proto.CompactTextString(m) }
-func (*Notify) ProtoMessage()               {}
-func (*Notify) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (x *HelperNebulaOutput) GetExited() bool {
+	if x != nil {
+		return x.Exited
+	}
+	return false
+}
 
-func (m *Notify) GetLogin() string {
-	if m != nil {
-		return m.Login
+func (x *HelperNebulaOutput) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*PingRequest)(nil), "nerf.PingRequest")
-	proto.RegisterType((*PingResponse)(nil), "nerf.PingResponse")
-	proto.RegisterType((*Request)(nil), "nerf.Request")
-	proto.RegisterType((*Response)(nil), "nerf.Response")
-	proto.RegisterType((*ApiResponse)(nil), "nerf.ApiResponse")
-	proto.RegisterType((*Notify)(nil), "nerf.Notify")
+type HelperNebulaStop struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pid       int32 `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	TimeoutMs int64 `protobuf:"varint,2,opt,name=timeoutMs,proto3" json:"timeoutMs,omitempty"`
+}
+
+func (x *HelperNebulaStop) Reset() {
+	*x = HelperNebulaStop{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nerf_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HelperNebulaStop) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HelperNebulaStop) ProtoMessage() {}
+
+func (x *HelperNebulaStop) ProtoReflect() protoreflect.Message {
+	mi := &file_nerf_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HelperNebulaStop.ProtoReflect.Descriptor instead.
+func (*HelperNebulaStop) Descriptor() ([]byte, []int) {
+	return file_nerf_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *HelperNebulaStop) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *HelperNebulaStop) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+var File_nerf_proto protoreflect.FileDescriptor
+
+var file_nerf_proto_rawDesc = []byte{
+	0x0a, 0x0a, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x04, 0x6e, 0x65,
+	0x72, 0x66, 0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
+	0x37, 0x0a, 0x0b, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x64, 0x61,
+	0x74, 0x61, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x6f, 0x67, 0x69, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x6c, 0x6f, 0x67, 0x69, 0x6e, 0x22, 0x22, 0x0a, 0x0c, 0x50, 0x69, 0x6e, 0x67,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x69, 0x0a, 0x07,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x6f, 0x67, 0x69, 0x6e,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x6f, 0x67, 0x69, 0x6e, 0x12, 0x14, 0x0a,
+	0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f,
+	0x6b, 0x65, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12,
+	0x16, 0x0a, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x22, 0xb6, 0x01, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x1a, 0x0a, 0x08,
+	0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x50, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x50, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x65, 0x61, 0x6d,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x74, 0x65, 0x61, 0x6d, 0x73, 0x12, 0x22,
+	0x0a, 0x0c, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x48, 0x6f, 0x75, 0x73, 0x65, 0x49, 0x50, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x48, 0x6f, 0x75, 0x73, 0x65,
+	0x49, 0x50, 0x12, 0x3c, 0x0a, 0x0e, 0x6e, 0x65, 0x62, 0x75, 0x6c, 0x61, 0x4d, 0x61, 0x6e, 0x69,
+	0x66, 0x65, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x6e, 0x65, 0x72,
+	0x66, 0x2e, 0x4e, 0x65, 0x62, 0x75, 0x6c, 0x61, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74,
+	0x52, 0x0e, 0x6e, 0x65, 0x62, 0x75, 0x6c, 0x61, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74,
+	0x22, 0x77, 0x0a, 0x0d, 0x4e, 0x65, 0x62, 0x75, 0x6c, 0x61, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x0a, 0x02, 0x6f,
+	0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x6f, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x61,
+	0x72, 0x63, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x72, 0x63, 0x68, 0x12,
+	0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72,
+	0x6c, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x22, 0x61, 0x0a, 0x0e, 0x4e, 0x65, 0x62,
+	0x75, 0x6c, 0x61, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x6d,
+	0x69, 0x6e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0a, 0x6d, 0x69, 0x6e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x2f, 0x0a, 0x08, 0x72,
+	0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e,
+	0x6e, 0x65, 0x72, 0x66, 0x2e, 0x4e, 0x65, 0x62, 0x75, 0x6c, 0x61, 0x52, 0x65, 0x6c, 0x65, 0x61,
+	0x73, 0x65, 0x52, 0x08, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x73, 0x22, 0x45, 0x0a, 0x0b,
+	0x41, 0x70, 0x69, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63,
+	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x50, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63,
+	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x50, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74,
+	0x65, 0x49, 0x50, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74,
+	0x65, 0x49, 0x50, 0x22, 0x1e, 0x0a, 0x06, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x79, 0x12, 0x14, 0x0a,
+	0x05, 0x6c, 0x6f, 0x67, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x6f,
+	0x67, 0x69, 0x6e, 0x22, 0xb8, 0x02, 0x0a, 0x05, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x24, 0x0a,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x10, 0x2e, 0x6e, 0x65,
+	0x72, 0x66, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1c, 0x0a,
+	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1a, 0x0a, 0x08, 0x72,
+	0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72,
+	0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50, 0x22, 0xb4, 0x01, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65,
+	0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x11, 0x0a,
+	0x0d, 0x54, 0x45, 0x41, 0x4d, 0x53, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x47, 0x45, 0x44, 0x10, 0x01,
+	0x12, 0x17, 0x0a, 0x13, 0x43, 0x45, 0x52, 0x54, 0x49, 0x46, 0x49, 0x43, 0x41, 0x54, 0x45, 0x5f,
+	0x52, 0x45, 0x56, 0x4f, 0x4b, 0x45, 0x44, 0x10, 0x02, 0x12, 0x15, 0x0a, 0x11, 0x46, 0x4f, 0x52,
+	0x43, 0x45, 0x44, 0x5f, 0x44, 0x49, 0x53, 0x43, 0x4f, 0x4e, 0x4e, 0x45, 0x43, 0x54, 0x10, 0x03,
+	0x12, 0x19, 0x0a, 0x15, 0x4d, 0x41, 0x49, 0x4e, 0x54, 0x45, 0x4e, 0x41, 0x4e, 0x43, 0x45, 0x5f,
+	0x53, 0x43, 0x48, 0x45, 0x44, 0x55, 0x4c, 0x45, 0x44, 0x10, 0x04, 0x12, 0x14, 0x0a, 0x10, 0x43,
+	0x4f, 0x4e, 0x46, 0x49, 0x47, 0x5f, 0x41, 0x56, 0x41, 0x49, 0x4c, 0x41, 0x42, 0x4c, 0x45, 0x10,
+	0x05, 0x12, 0x15, 0x0a, 0x11, 0x45, 0x4e, 0x44, 0x50, 0x4f, 0x49, 0x4e, 0x54, 0x5f, 0x53, 0x57,
+	0x49, 0x54, 0x43, 0x48, 0x45, 0x44, 0x10, 0x06, 0x12, 0x14, 0x0a, 0x10, 0x4e, 0x45, 0x42, 0x55,
+	0x4c, 0x41, 0x5f, 0x52, 0x45, 0x53, 0x54, 0x41, 0x52, 0x54, 0x45, 0x44, 0x10, 0x07, 0x22, 0x9e,
+	0x02, 0x0a, 0x0c, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x12,
+	0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x48, 0x6f, 0x73, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x48, 0x6f, 0x73,
+	0x74, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50, 0x12, 0x12, 0x0a,
+	0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x70, 0x6f, 0x72,
+	0x74, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x16, 0x0a,
+	0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x77,
+	0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x12,
+	0x16, 0x0a, 0x06, 0x6a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x06, 0x6a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x61, 0x63, 0x68,
+	0x61, 0x62, 0x6c, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x72, 0x65, 0x61, 0x63,
+	0x68, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74,
+	0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x22,
+	0x45, 0x0a, 0x11, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x30, 0x0a, 0x09, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x45,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x65, 0x6e, 0x64,
+	0x70, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x22, 0xab, 0x03, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x12, 0x28, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x12, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x2e, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73,
+	0x69, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x73, 0x69, 0x6e, 0x63,
+	0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x48, 0x6f, 0x73, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x48, 0x6f, 0x73,
+	0x74, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50, 0x12,
+	0x1a, 0x0a, 0x08, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x50, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x50, 0x12, 0x14, 0x0a, 0x05, 0x74,
+	0x65, 0x61, 0x6d, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x74, 0x65, 0x61, 0x6d,
+	0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x20, 0x0a, 0x0b, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x98, 0x01, 0x0a, 0x05, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x12, 0x10, 0x0a, 0x0c, 0x44, 0x49, 0x53, 0x43, 0x4f, 0x4e, 0x4e, 0x45, 0x43,
+	0x54, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x44, 0x49, 0x53, 0x43, 0x4f, 0x56, 0x45,
+	0x52, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x12, 0x0a, 0x0e, 0x41, 0x55, 0x54, 0x48, 0x45, 0x4e,
+	0x54, 0x49, 0x43, 0x41, 0x54, 0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x43, 0x4f,
+	0x4e, 0x46, 0x49, 0x47, 0x55, 0x52, 0x49, 0x4e, 0x47, 0x10, 0x03, 0x12, 0x13, 0x0a, 0x0f, 0x53,
+	0x54, 0x41, 0x52, 0x54, 0x49, 0x4e, 0x47, 0x5f, 0x4e, 0x45, 0x42, 0x55, 0x4c, 0x41, 0x10, 0x04,
+	0x12, 0x0d, 0x0a, 0x09, 0x43, 0x4f, 0x4e, 0x4e, 0x45, 0x43, 0x54, 0x45, 0x44, 0x10, 0x05, 0x12,
+	0x10, 0x0a, 0x0c, 0x52, 0x45, 0x43, 0x4f, 0x4e, 0x4e, 0x45, 0x43, 0x54, 0x49, 0x4e, 0x47, 0x10,
+	0x06, 0x12, 0x11, 0x0a, 0x0d, 0x44, 0x49, 0x53, 0x43, 0x4f, 0x4e, 0x4e, 0x45, 0x43, 0x54, 0x49,
+	0x4e, 0x47, 0x10, 0x07, 0x22, 0x29, 0x0a, 0x0b, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72, 0x52, 0x6f,
+	0x75, 0x74, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50, 0x22,
+	0x65, 0x0a, 0x11, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x53, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x49, 0x50,
+	0x12, 0x20, 0x0a, 0x0b, 0x6e, 0x61, 0x6d, 0x65, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0b, 0x6e, 0x61, 0x6d, 0x65, 0x53, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x61, 0x76, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x04, 0x73, 0x61, 0x76, 0x65, 0x22, 0x26, 0x0a, 0x0c, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72,
+	0x4e, 0x65, 0x62, 0x75, 0x6c, 0x61, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x22, 0x80,
+	0x01, 0x0a, 0x12, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72, 0x4e, 0x65, 0x62, 0x75, 0x6c, 0x61, 0x4f,
+	0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x70, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x03, 0x70, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12,
+	0x12, 0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6c,
+	0x69, 0x6e, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x78, 0x69, 0x74, 0x65, 0x64, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x06, 0x65, 0x78, 0x69, 0x74, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x22, 0x42, 0x0a, 0x10, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72, 0x4e, 0x65, 0x62, 0x75, 0x6c,
+	0x61, 0x53, 0x74, 0x6f, 0x70, 0x12, 0x10, 0x0a, 0x03, 0x70, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x03, 0x70, 0x69, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x4d, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65,
+	0x6f, 0x75, 0x74, 0x4d, 0x73, 0x32, 0x85, 0x03, 0x0a, 0x03, 0x41, 0x70, 0x69, 0x12, 0x2b, 0x0a,
+	0x07, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x0d, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x41,
+	0x70, 0x69, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x32, 0x0a, 0x0a, 0x44, 0x69,
+	0x73, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x0c, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e,
+	0x4e, 0x6f, 0x74, 0x69, 0x66, 0x79, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x2d,
+	0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x11, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x50, 0x69,
+	0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x6e, 0x65, 0x72, 0x66,
+	0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a,
+	0x0b, 0x57, 0x61, 0x74, 0x63, 0x68, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x0c, 0x2e, 0x6e,
+	0x65, 0x72, 0x66, 0x2e, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x79, 0x1a, 0x0b, 0x2e, 0x6e, 0x65, 0x72,
+	0x66, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x12, 0x35, 0x0a, 0x0c, 0x47, 0x65, 0x74,
+	0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x12, 0x0c, 0x2e, 0x6e, 0x65, 0x72, 0x66,
+	0x2e, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x79, 0x1a, 0x17, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x45,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x27, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0c, 0x2e,
+	0x6e, 0x65, 0x72, 0x66, 0x2e, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x79, 0x1a, 0x0c, 0x2e, 0x6e, 0x65,
+	0x72, 0x66, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x2b, 0x0a, 0x0b, 0x57, 0x61, 0x74,
+	0x63, 0x68, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0c, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e,
+	0x4e, 0x6f, 0x74, 0x69, 0x66, 0x79, 0x1a, 0x0c, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x30, 0x01, 0x12, 0x35, 0x0a, 0x0d, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c,
+	0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x0c, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x4e,
+	0x6f, 0x74, 0x69, 0x66, 0x79, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x32, 0xc2, 0x01,
+	0x0a, 0x06, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12, 0x28, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x6e,
+	0x65, 0x63, 0x74, 0x12, 0x0d, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x32, 0x0a, 0x0a, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x12, 0x0c, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x79, 0x1a, 0x16,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x2d, 0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x11,
+	0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x12, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2b, 0x0a, 0x0b, 0x57, 0x61, 0x74, 0x63, 0x68, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x73, 0x12, 0x0d, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x30, 0x01, 0x32, 0xf9, 0x02, 0x0a, 0x06, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72, 0x12, 0x35, 0x0a,
+	0x08, 0x41, 0x64, 0x64, 0x52, 0x6f, 0x75, 0x74, 0x65, 0x12, 0x11, 0x2e, 0x6e, 0x65, 0x72, 0x66,
+	0x2e, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72, 0x52, 0x6f, 0x75, 0x74, 0x65, 0x1a, 0x16, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45,
+	0x6d, 0x70, 0x74, 0x79, 0x12, 0x38, 0x0a, 0x0b, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x52, 0x6f,
+	0x75, 0x74, 0x65, 0x12, 0x11, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x48, 0x65, 0x6c, 0x70, 0x65,
+	0x72, 0x52, 0x6f, 0x75, 0x74, 0x65, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x41,
+	0x0a, 0x0e, 0x53, 0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73,
+	0x12, 0x17, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72, 0x4e, 0x61,
+	0x6d, 0x65, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x12, 0x3e, 0x0a, 0x11, 0x52, 0x65, 0x76, 0x65, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x53,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x12, 0x11, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x48, 0x65,
+	0x6c, 0x70, 0x65, 0x72, 0x52, 0x6f, 0x75, 0x74, 0x65, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x12, 0x3d, 0x0a, 0x0b, 0x53, 0x74, 0x61, 0x72, 0x74, 0x4e, 0x65, 0x62, 0x75, 0x6c, 0x61,
+	0x12, 0x12, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72, 0x4e, 0x65,
+	0x62, 0x75, 0x6c, 0x61, 0x1a, 0x18, 0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x48, 0x65, 0x6c, 0x70,
+	0x65, 0x72, 0x4e, 0x65, 0x62, 0x75, 0x6c, 0x61, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x30, 0x01,
+	0x12, 0x3c, 0x0a, 0x0a, 0x53, 0x74, 0x6f, 0x70, 0x4e, 0x65, 0x62, 0x75, 0x6c, 0x61, 0x12, 0x16,
+	0x2e, 0x6e, 0x65, 0x72, 0x66, 0x2e, 0x48, 0x65, 0x6c, 0x70, 0x65, 0x72, 0x4e, 0x65, 0x62, 0x75,
+	0x6c, 0x61, 0x53, 0x74, 0x6f, 0x70, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x1a,
+	0x5a, 0x18, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x6f, 0x6e,
+	0x33, 0x31, 0x33, 0x33, 0x37, 0x2f, 0x6e, 0x65, 0x72, 0x66, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_nerf_proto_rawDescOnce sync.Once
+	file_nerf_proto_rawDescData = file_nerf_proto_rawDesc
+)
+
+func file_nerf_proto_rawDescGZIP() []byte {
+	file_nerf_proto_rawDescOnce.Do(func() {
+		file_nerf_proto_rawDescData = protoimpl.X.CompressGZIP(file_nerf_proto_rawDescData)
+	})
+	return file_nerf_proto_rawDescData
+}
+
+var file_nerf_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_nerf_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_nerf_proto_goTypes = []interface{}{
+	(Event_Type)(0),            // 0: nerf.Event.Type
+	(Status_State)(0),          // 1: nerf.Status.State
+	(*PingRequest)(nil),        // 2: nerf.PingRequest
+	(*PingResponse)(nil),       // 3: nerf.PingResponse
+	(*Request)(nil),            // 4: nerf.Request
+	(*Response)(nil),           // 5: nerf.Response
+	(*NebulaRelease)(nil),      // 6: nerf.NebulaRelease
+	(*NebulaManifest)(nil),     // 7: nerf.NebulaManifest
+	(*ApiResponse)(nil),        // 8: nerf.ApiResponse
+	(*Notify)(nil),             // 9: nerf.Notify
+	(*Event)(nil),              // 10: nerf.Event
+	(*EndpointInfo)(nil),       // 11: nerf.EndpointInfo
+	(*EndpointsResponse)(nil),  // 12: nerf.EndpointsResponse
+	(*Status)(nil),             // 13: nerf.Status
+	(*HelperRoute)(nil),        // 14: nerf.HelperRoute
+	(*HelperNameServers)(nil),  // 15: nerf.HelperNameServers
+	(*HelperNebula)(nil),       // 16: nerf.HelperNebula
+	(*HelperNebulaOutput)(nil), // 17: nerf.HelperNebulaOutput
+	(*HelperNebulaStop)(nil),   // 18: nerf.HelperNebulaStop
+	(*emptypb.Empty)(nil),      // 19: google.protobuf.Empty
+}
+var file_nerf_proto_depIdxs = []int32{
+	7,  // 0: nerf.Response.nebulaManifest:type_name -> nerf.NebulaManifest
+	6,  // 1: nerf.NebulaManifest.releases:type_name -> nerf.NebulaRelease
+	0,  // 2: nerf.Event.type:type_name -> nerf.Event.Type
+	11, // 3: nerf.EndpointsResponse.endpoints:type_name -> nerf.EndpointInfo
+	1,  // 4: nerf.Status.state:type_name -> nerf.Status.State
+	4,  // 5: nerf.Api.Connect:input_type -> nerf.Request
+	9,  // 6: nerf.Api.Disconnect:input_type -> nerf.Notify
+	2,  // 7: nerf.Api.Ping:input_type -> nerf.PingRequest
+	9,  // 8: nerf.Api.WatchEvents:input_type -> nerf.Notify
+	9,  // 9: nerf.Api.GetEndpoints:input_type -> nerf.Notify
+	9,  // 10: nerf.Api.GetStatus:input_type -> nerf.Notify
+	9,  // 11: nerf.Api.WatchStatus:input_type -> nerf.Notify
+	9,  // 12: nerf.Api.CancelConnect:input_type -> nerf.Notify
+	4,  // 13: nerf.Server.Connect:input_type -> nerf.Request
+	9,  // 14: nerf.Server.Disconnect:input_type -> nerf.Notify
+	2,  // 15: nerf.Server.Ping:input_type -> nerf.PingRequest
+	4,  // 16: nerf.Server.WatchEvents:input_type -> nerf.Request
+	14, // 17: nerf.Helper.AddRoute:input_type -> nerf.HelperRoute
+	14, // 18: nerf.Helper.DeleteRoute:input_type -> nerf.HelperRoute
+	15, // 19: nerf.Helper.SetNameServers:input_type -> nerf.HelperNameServers
+	14, // 20: nerf.Helper.RevertNameServers:input_type -> nerf.HelperRoute
+	16, // 21: nerf.Helper.StartNebula:input_type -> nerf.HelperNebula
+	18, // 22: nerf.Helper.StopNebula:input_type -> nerf.HelperNebulaStop
+	8,  // 23: nerf.Api.Connect:output_type -> nerf.ApiResponse
+	19, // 24: nerf.Api.Disconnect:output_type -> google.protobuf.Empty
+	3,  // 25: nerf.Api.Ping:output_type -> nerf.PingResponse
+	10, // 26: nerf.Api.WatchEvents:output_type -> nerf.Event
+	12, // 27: nerf.Api.GetEndpoints:output_type -> nerf.EndpointsResponse
+	13, // 28: nerf.Api.GetStatus:output_type -> nerf.Status
+	13, // 29: nerf.Api.WatchStatus:output_type -> nerf.Status
+	19, // 30: nerf.Api.CancelConnect:output_type -> google.protobuf.Empty
+	5,  // 31: nerf.Server.Connect:output_type -> nerf.Response
+	19, // 32: nerf.Server.Disconnect:output_type -> google.protobuf.Empty
+	3,  // 33: nerf.Server.Ping:output_type -> nerf.PingResponse
+	10, // 34: nerf.Server.WatchEvents:output_type -> nerf.Event
+	19, // 35: nerf.Helper.AddRoute:output_type -> google.protobuf.Empty
+	19, // 36: nerf.Helper.DeleteRoute:output_type -> google.protobuf.Empty
+	19, // 37: nerf.Helper.SetNameServers:output_type -> google.protobuf.Empty
+	19, // 38: nerf.Helper.RevertNameServers:output_type -> google.protobuf.Empty
+	17, // 39: nerf.Helper.StartNebula:output_type -> nerf.HelperNebulaOutput
+	19, // 40: nerf.Helper.StopNebula:output_type -> google.protobuf.Empty
+	23, // [23:41] is the sub-list for method output_type
+	5,  // [5:23] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_nerf_proto_init() }
+func file_nerf_proto_init() {
+	if File_nerf_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_nerf_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PingRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PingResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Request); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NebulaRelease); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NebulaManifest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ApiResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Notify); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Event); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EndpointInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EndpointsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Status); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HelperRoute); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HelperNameServers); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HelperNebula); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HelperNebulaOutput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nerf_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HelperNebulaStop); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_nerf_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   3,
+		},
+		GoTypes:           file_nerf_proto_goTypes,
+		DependencyIndexes: file_nerf_proto_depIdxs,
+		EnumInfos:         file_nerf_proto_enumTypes,
+		MessageInfos:      file_nerf_proto_msgTypes,
+	}.Build()
+	File_nerf_proto = out.File
+	file_nerf_proto_rawDesc = nil
+	file_nerf_proto_goTypes = nil
+	file_nerf_proto_depIdxs = nil
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ context.Context
-var _ grpc.ClientConn
+var _ grpc.ClientConnInterface
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
-
-// Client API for Api service
+const _ = grpc.SupportPackageIsVersion6
 
+// ApiClient is the client API for Api service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type ApiClient interface {
 	Connect(ctx context.Context, in *Request, opts ...grpc.CallOption) (*ApiResponse, error)
-	Disconnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*google_protobuf.Empty, error)
+	Disconnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
+	WatchEvents(ctx context.Context, in *Notify, opts ...grpc.CallOption) (Api_WatchEventsClient, error)
+	GetEndpoints(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*EndpointsResponse, error)
+	GetStatus(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*Status, error)
+	WatchStatus(ctx context.Context, in *Notify, opts ...grpc.CallOption) (Api_WatchStatusClient, error)
+	CancelConnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type apiClient struct {
-	cc *grpc.ClientConn
+	cc grpc.ClientConnInterface
 }
 
-func NewApiClient(cc *grpc.ClientConn) ApiClient {
+func NewApiClient(cc grpc.ClientConnInterface) ApiClient {
 	return &apiClient{cc}
 }
 
 func (c *apiClient) Connect(ctx context.Context, in *Request, opts ...grpc.CallOption) (*ApiResponse, error) {
 	out := new(ApiResponse)
-	err := grpc.Invoke(ctx, "/nerf.Api/Connect", in, out, c.cc, opts...)
+	err := c.cc.Invoke(ctx, "/nerf.Api/Connect", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) Disconnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/nerf.Api/Disconnect", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
+	out := new(PingResponse)
+	err := c.cc.Invoke(ctx, "/nerf.Api/Ping", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) WatchEvents(ctx context.Context, in *Notify, opts ...grpc.CallOption) (Api_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Api_serviceDesc.Streams[0], "/nerf.Api/WatchEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &apiWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Api_WatchEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type apiWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *apiWatchEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *apiClient) GetEndpoints(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*EndpointsResponse, error) {
+	out := new(EndpointsResponse)
+	err := c.cc.Invoke(ctx, "/nerf.Api/GetEndpoints", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetStatus(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/nerf.Api/GetStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) WatchStatus(ctx context.Context, in *Notify, opts ...grpc.CallOption) (Api_WatchStatusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Api_serviceDesc.Streams[1], "/nerf.Api/WatchStatus", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &apiWatchStatusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Api_WatchStatusClient interface {
+	Recv() (*Status, error)
+	grpc.ClientStream
+}
+
+type apiWatchStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *apiWatchStatusClient) Recv() (*Status, error) {
+	m := new(Status)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *apiClient) CancelConnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/nerf.Api/CancelConnect", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *apiClient) Disconnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*google_protobuf.Empty, error) {
-	out := new(google_protobuf.Empty)
-	err := grpc.Invoke(ctx, "/nerf.Api/Disconnect", in, out, c.cc, opts...)
-	if err != nil {
+// ApiServer is the server API for Api service.
+type ApiServer interface {
+	Connect(context.Context, *Request) (*ApiResponse, error)
+	Disconnect(context.Context, *Notify) (*emptypb.Empty, error)
+	Ping(context.Context, *PingRequest) (*PingResponse, error)
+	WatchEvents(*Notify, Api_WatchEventsServer) error
+	GetEndpoints(context.Context, *Notify) (*EndpointsResponse, error)
+	GetStatus(context.Context, *Notify) (*Status, error)
+	WatchStatus(*Notify, Api_WatchStatusServer) error
+	CancelConnect(context.Context, *Notify) (*emptypb.Empty, error)
+}
+
+// UnimplementedApiServer can be embedded to have forward compatible implementations.
+type UnimplementedApiServer struct {
+}
+
+func (*UnimplementedApiServer) Connect(context.Context, *Request) (*ApiResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
+func (*UnimplementedApiServer) Disconnect(context.Context, *Notify) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Disconnect not implemented")
+}
+func (*UnimplementedApiServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (*UnimplementedApiServer) WatchEvents(*Notify, Api_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (*UnimplementedApiServer) GetEndpoints(context.Context, *Notify) (*EndpointsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEndpoints not implemented")
+}
+func (*UnimplementedApiServer) GetStatus(context.Context, *Notify) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (*UnimplementedApiServer) WatchStatus(*Notify, Api_WatchStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
+}
+func (*UnimplementedApiServer) CancelConnect(context.Context, *Notify) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelConnect not implemented")
+}
+
+func RegisterApiServer(s *grpc.Server, srv ApiServer) {
+	s.RegisterService(&_Api_serviceDesc, srv)
+}
+
+func _Api_Connect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).Connect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nerf.Api/Connect",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).Connect(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_Disconnect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Notify)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).Disconnect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nerf.Api/Disconnect",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).Disconnect(ctx, req.(*Notify))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(ApiServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nerf.Api/Ping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *apiClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
-	out := new(PingResponse)
-	err := grpc.Invoke(ctx, "/nerf.Api/Ping", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func _Api_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Notify)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
 	}
-	return out, nil
+	return srv.(ApiServer).WatchEvents(m, &apiWatchEventsServer{stream})
 }
 
-// Server API for Api service
+type Api_WatchEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
 
-type ApiServer interface {
-	Connect(context.Context, *Request) (*ApiResponse, error)
-	Disconnect(context.Context, *Notify) (*google_protobuf.Empty, error)
-	Ping(context.Context, *PingRequest) (*PingResponse, error)
+type apiWatchEventsServer struct {
+	grpc.ServerStream
 }
 
-func RegisterApiServer(s *grpc.Server, srv ApiServer) {
-	s.RegisterService(&_Api_serviceDesc, srv)
+func (x *apiWatchEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
 }
 
-func _Api_Connect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Request)
+func _Api_GetEndpoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Notify)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ApiServer).Connect(ctx, in)
+		return srv.(ApiServer).GetEndpoints(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/nerf.Api/Connect",
+		FullMethod: "/nerf.Api/GetEndpoints",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ApiServer).Connect(ctx, req.(*Request))
+		return srv.(ApiServer).GetEndpoints(ctx, req.(*Notify))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Api_Disconnect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _Api_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Notify)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ApiServer).Disconnect(ctx, in)
+		return srv.(ApiServer).GetStatus(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/nerf.Api/Disconnect",
+		FullMethod: "/nerf.Api/GetStatus",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ApiServer).Disconnect(ctx, req.(*Notify))
+		return srv.(ApiServer).GetStatus(ctx, req.(*Notify))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Api_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PingRequest)
+func _Api_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Notify)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ApiServer).WatchStatus(m, &apiWatchStatusServer{stream})
+}
+
+type Api_WatchStatusServer interface {
+	Send(*Status) error
+	grpc.ServerStream
+}
+
+type apiWatchStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *apiWatchStatusServer) Send(m *Status) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Api_CancelConnect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Notify)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ApiServer).Ping(ctx, in)
+		return srv.(ApiServer).CancelConnect(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/nerf.Api/Ping",
+		FullMethod: "/nerf.Api/CancelConnect",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ApiServer).Ping(ctx, req.(*PingRequest))
+		return srv.(ApiServer).CancelConnect(ctx, req.(*Notify))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -324,39 +2165,64 @@ var _Api_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _Api_Ping_Handler,
 		},
+		{
+			MethodName: "GetEndpoints",
+			Handler:    _Api_GetEndpoints_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _Api_GetStatus_Handler,
+		},
+		{
+			MethodName: "CancelConnect",
+			Handler:    _Api_CancelConnect_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Api_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _Api_WatchStatus_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "nerf.proto",
 }
 
-// Client API for Server service
-
+// ServerClient is the client API for Server service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type ServerClient interface {
 	Connect(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
-	Disconnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*google_protobuf.Empty, error)
+	Disconnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
+	WatchEvents(ctx context.Context, in *Request, opts ...grpc.CallOption) (Server_WatchEventsClient, error)
 }
 
 type serverClient struct {
-	cc *grpc.ClientConn
+	cc grpc.ClientConnInterface
 }
 
-func NewServerClient(cc *grpc.ClientConn) ServerClient {
+func NewServerClient(cc grpc.ClientConnInterface) ServerClient {
 	return &serverClient{cc}
 }
 
 func (c *serverClient) Connect(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error) {
 	out := new(Response)
-	err := grpc.Invoke(ctx, "/nerf.Server/Connect", in, out, c.cc, opts...)
+	err := c.cc.Invoke(ctx, "/nerf.Server/Connect", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *serverClient) Disconnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*google_protobuf.Empty, error) {
-	out := new(google_protobuf.Empty)
-	err := grpc.Invoke(ctx, "/nerf.Server/Disconnect", in, out, c.cc, opts...)
+func (c *serverClient) Disconnect(ctx context.Context, in *Notify, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/nerf.Server/Disconnect", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -365,19 +2231,68 @@ func (c *serverClient) Disconnect(ctx context.Context, in *Notify, opts ...grpc.
 
 func (c *serverClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
 	out := new(PingResponse)
-	err := grpc.Invoke(ctx, "/nerf.Server/Ping", in, out, c.cc, opts...)
+	err := c.cc.Invoke(ctx, "/nerf.Server/Ping", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// Server API for Server service
+func (c *serverClient) WatchEvents(ctx context.Context, in *Request, opts ...grpc.CallOption) (Server_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Server_serviceDesc.Streams[0], "/nerf.Server/WatchEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &serverWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Server_WatchEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type serverWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *serverWatchEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
 
+// ServerServer is the server API for Server service.
 type ServerServer interface {
 	Connect(context.Context, *Request) (*Response, error)
-	Disconnect(context.Context, *Notify) (*google_protobuf.Empty, error)
+	Disconnect(context.Context, *Notify) (*emptypb.Empty, error)
 	Ping(context.Context, *PingRequest) (*PingResponse, error)
+	WatchEvents(*Request, Server_WatchEventsServer) error
+}
+
+// UnimplementedServerServer can be embedded to have forward compatible implementations.
+type UnimplementedServerServer struct {
+}
+
+func (*UnimplementedServerServer) Connect(context.Context, *Request) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
+func (*UnimplementedServerServer) Disconnect(context.Context, *Notify) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Disconnect not implemented")
+}
+func (*UnimplementedServerServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (*UnimplementedServerServer) WatchEvents(*Request, Server_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
 }
 
 func RegisterServerServer(s *grpc.Server, srv ServerServer) {
@@ -438,6 +2353,27 @@ func _Server_Ping_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Server_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Request)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServerServer).WatchEvents(m, &serverWatchEventsServer{stream})
+}
+
+type Server_WatchEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type serverWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *serverWatchEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _Server_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "nerf.Server",
 	HandlerType: (*ServerServer)(nil),
@@ -455,34 +2391,292 @@ var _Server_serviceDesc = grpc.ServiceDesc{
 			Handler:    _Server_Ping_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Server_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "nerf.proto",
 }
 
-func init() { proto.RegisterFile("nerf.proto", fileDescriptor0) }
-
-var fileDescriptor0 = []byte{
-	// 341 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x91, 0x4f, 0x4b, 0xc3, 0x40,
-	0x10, 0xc5, 0x1b, 0x13, 0xd3, 0x3a, 0xad, 0x82, 0x83, 0x94, 0x10, 0x41, 0xca, 0x9e, 0x8a, 0x60,
-	0x0a, 0xfe, 0xc1, 0x73, 0xd1, 0x82, 0xbd, 0x48, 0xa9, 0x9f, 0xa0, 0x8d, 0x93, 0xb8, 0x98, 0xee,
-	0xc6, 0x64, 0x23, 0xf6, 0xb3, 0xf8, 0x65, 0x25, 0xbb, 0x49, 0x8c, 0x52, 0xaf, 0xde, 0xf6, 0x0d,
-	0xf3, 0x7e, 0xf3, 0x92, 0x07, 0x20, 0x28, 0x8b, 0x82, 0x34, 0x93, 0x4a, 0xa2, 0x53, 0xbe, 0xfd,
-	0xd3, 0x58, 0xca, 0x38, 0xa1, 0x89, 0x9e, 0xad, 0x8b, 0x68, 0x42, 0x9b, 0x54, 0x6d, 0xcd, 0x0a,
-	0xbb, 0x85, 0xfe, 0x82, 0x8b, 0x78, 0x49, 0x6f, 0x05, 0xe5, 0x0a, 0x11, 0x9c, 0xe7, 0x95, 0x5a,
-	0x79, 0xd6, 0xc8, 0x1a, 0xdb, 0x4b, 0xfd, 0xc6, 0x13, 0xd8, 0x4f, 0x64, 0xcc, 0x85, 0xb7, 0x37,
-	0xb2, 0xc6, 0x07, 0x4b, 0x23, 0x18, 0x83, 0x81, 0x31, 0xe6, 0xa9, 0x14, 0x39, 0xed, 0x72, 0xb2,
-	0x1b, 0xe8, 0xd6, 0xe0, 0x06, 0x62, 0xb5, 0x20, 0xe5, 0x54, 0xc9, 0x57, 0x6a, 0xd0, 0x5a, 0xb0,
-	0x0f, 0xe8, 0x35, 0xd8, 0x21, 0xb8, 0xa1, 0x14, 0x11, 0x8f, 0x2b, 0x63, 0xa5, 0xd0, 0x87, 0x5e,
-	0x98, 0x70, 0x12, 0x6a, 0xbe, 0xa8, 0xcc, 0x8d, 0xd6, 0x54, 0x5a, 0x6d, 0x72, 0xcf, 0x1e, 0xd9,
-	0x9a, 0x5a, 0x0a, 0x64, 0x30, 0x48, 0x78, 0xfc, 0xa2, 0x1e, 0x64, 0x91, 0xd3, 0x7c, 0xe1, 0x39,
-	0xda, 0xf5, 0x63, 0xc6, 0x66, 0xd0, 0x9f, 0xa6, 0xbc, 0x39, 0xde, 0x3e, 0x62, 0xfd, 0x3a, 0xe2,
-	0x43, 0x2f, 0xa3, 0x8d, 0x54, 0xf4, 0x1d, 0xa0, 0xd6, 0xec, 0x0c, 0xdc, 0x47, 0xa9, 0x78, 0xb4,
-	0xdd, 0xfd, 0xd9, 0x97, 0x9f, 0x16, 0xd8, 0xd3, 0x94, 0xe3, 0x05, 0x74, 0xef, 0xa4, 0x10, 0x14,
-	0x2a, 0x3c, 0x0c, 0x74, 0x6f, 0xd5, 0xef, 0xf2, 0x8f, 0x8d, 0x6c, 0x85, 0x61, 0x1d, 0xbc, 0x06,
-	0xb8, 0xe7, 0x79, 0x58, 0x39, 0x06, 0x66, 0xc5, 0x1c, 0xf2, 0x87, 0x81, 0x69, 0x39, 0xa8, 0x5b,
-	0x0e, 0x66, 0x65, 0xcb, 0xac, 0x83, 0x13, 0x70, 0xca, 0xa2, 0xb0, 0x42, 0xb6, 0xda, 0xf6, 0xb1,
-	0x3d, 0xaa, 0xcf, 0x94, 0xe9, 0xdc, 0x27, 0xca, 0xde, 0x29, 0xc3, 0xf3, 0x3f, 0x03, 0x1e, 0xd5,
-	0xf2, 0x9f, 0xd3, 0xad, 0x5d, 0x8d, 0xb8, 0xfa, 0x0a, 0x00, 0x00, 0xff, 0xff, 0x60, 0x97, 0x23,
-	0x37, 0xe8, 0x02, 0x00, 0x00,
+// HelperClient is the client API for Helper service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type HelperClient interface {
+	AddRoute(ctx context.Context, in *HelperRoute, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteRoute(ctx context.Context, in *HelperRoute, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	SetNameServers(ctx context.Context, in *HelperNameServers, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	RevertNameServers(ctx context.Context, in *HelperRoute, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	StartNebula(ctx context.Context, in *HelperNebula, opts ...grpc.CallOption) (Helper_StartNebulaClient, error)
+	StopNebula(ctx context.Context, in *HelperNebulaStop, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type helperClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHelperClient(cc grpc.ClientConnInterface) HelperClient {
+	return &helperClient{cc}
+}
+
+func (c *helperClient) AddRoute(ctx context.Context, in *HelperRoute, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/nerf.Helper/AddRoute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *helperClient) DeleteRoute(ctx context.Context, in *HelperRoute, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/nerf.Helper/DeleteRoute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *helperClient) SetNameServers(ctx context.Context, in *HelperNameServers, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/nerf.Helper/SetNameServers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *helperClient) RevertNameServers(ctx context.Context, in *HelperRoute, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/nerf.Helper/RevertNameServers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *helperClient) StartNebula(ctx context.Context, in *HelperNebula, opts ...grpc.CallOption) (Helper_StartNebulaClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Helper_serviceDesc.Streams[0], "/nerf.Helper/StartNebula", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &helperStartNebulaClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Helper_StartNebulaClient interface {
+	Recv() (*HelperNebulaOutput, error)
+	grpc.ClientStream
+}
+
+type helperStartNebulaClient struct {
+	grpc.ClientStream
+}
+
+func (x *helperStartNebulaClient) Recv() (*HelperNebulaOutput, error) {
+	m := new(HelperNebulaOutput)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *helperClient) StopNebula(ctx context.Context, in *HelperNebulaStop, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/nerf.Helper/StopNebula", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HelperServer is the server API for Helper service.
+type HelperServer interface {
+	AddRoute(context.Context, *HelperRoute) (*emptypb.Empty, error)
+	DeleteRoute(context.Context, *HelperRoute) (*emptypb.Empty, error)
+	SetNameServers(context.Context, *HelperNameServers) (*emptypb.Empty, error)
+	RevertNameServers(context.Context, *HelperRoute) (*emptypb.Empty, error)
+	StartNebula(*HelperNebula, Helper_StartNebulaServer) error
+	StopNebula(context.Context, *HelperNebulaStop) (*emptypb.Empty, error)
+}
+
+// UnimplementedHelperServer can be embedded to have forward compatible implementations.
+type UnimplementedHelperServer struct {
+}
+
+func (*UnimplementedHelperServer) AddRoute(context.Context, *HelperRoute) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddRoute not implemented")
+}
+func (*UnimplementedHelperServer) DeleteRoute(context.Context, *HelperRoute) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRoute not implemented")
+}
+func (*UnimplementedHelperServer) SetNameServers(context.Context, *HelperNameServers) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetNameServers not implemented")
+}
+func (*UnimplementedHelperServer) RevertNameServers(context.Context, *HelperRoute) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevertNameServers not implemented")
+}
+func (*UnimplementedHelperServer) StartNebula(*HelperNebula, Helper_StartNebulaServer) error {
+	return status.Errorf(codes.Unimplemented, "method StartNebula not implemented")
+}
+func (*UnimplementedHelperServer) StopNebula(context.Context, *HelperNebulaStop) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopNebula not implemented")
+}
+
+func RegisterHelperServer(s *grpc.Server, srv HelperServer) {
+	s.RegisterService(&_Helper_serviceDesc, srv)
+}
+
+func _Helper_AddRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HelperRoute)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HelperServer).AddRoute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nerf.Helper/AddRoute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HelperServer).AddRoute(ctx, req.(*HelperRoute))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Helper_DeleteRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HelperRoute)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HelperServer).DeleteRoute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nerf.Helper/DeleteRoute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HelperServer).DeleteRoute(ctx, req.(*HelperRoute))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Helper_SetNameServers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HelperNameServers)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HelperServer).SetNameServers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nerf.Helper/SetNameServers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HelperServer).SetNameServers(ctx, req.(*HelperNameServers))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Helper_RevertNameServers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HelperRoute)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HelperServer).RevertNameServers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nerf.Helper/RevertNameServers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HelperServer).RevertNameServers(ctx, req.(*HelperRoute))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Helper_StartNebula_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(HelperNebula)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HelperServer).StartNebula(m, &helperStartNebulaServer{stream})
+}
+
+type Helper_StartNebulaServer interface {
+	Send(*HelperNebulaOutput) error
+	grpc.ServerStream
+}
+
+type helperStartNebulaServer struct {
+	grpc.ServerStream
+}
+
+func (x *helperStartNebulaServer) Send(m *HelperNebulaOutput) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Helper_StopNebula_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HelperNebulaStop)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HelperServer).StopNebula(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nerf.Helper/StopNebula",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HelperServer).StopNebula(ctx, req.(*HelperNebulaStop))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Helper_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "nerf.Helper",
+	HandlerType: (*HelperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddRoute",
+			Handler:    _Helper_AddRoute_Handler,
+		},
+		{
+			MethodName: "DeleteRoute",
+			Handler:    _Helper_DeleteRoute_Handler,
+		},
+		{
+			MethodName: "SetNameServers",
+			Handler:    _Helper_SetNameServers_Handler,
+		},
+		{
+			MethodName: "RevertNameServers",
+			Handler:    _Helper_RevertNameServers_Handler,
+		},
+		{
+			MethodName: "StopNebula",
+			Handler:    _Helper_StopNebula_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StartNebula",
+			Handler:       _Helper_StartNebula_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "nerf.proto",
 }
@@ -2,17 +2,24 @@ package nerf
 
 import (
 	"context"
-	"os"
-	"os/signal"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
 	"path"
-	"syscall"
+	"sync"
 	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
 	githuboauth "golang.org/x/oauth2/github"
 	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 // Cfg is a global configuration for Nerf client
@@ -30,148 +37,855 @@ var DNSAutoDiscoverZone string
 
 // Config struct to store all the relevant data for a client
 type Config struct {
-	Logger           *zap.Logger
-	OAuth            *oauth2.Config
-	Token            string
-	ListenAddr       string
-	Login            string
-	Endpoints        map[string]Endpoint
-	CurrentEndpoint  *Endpoint
-	SavedNameServers []string
-	NebulaPid        *int
-	Connected        bool
-	ClientIP         string
+	Logger              *zap.Logger
+	OAuth               *oauth2.Config
+	DeviceAuthURL       string
+	Token               string
+	ListenAddr          string
+	ApiGroup            string
+	AuthTimeout         time.Duration
+	Login               string
+	Endpoints           map[string]Endpoint
+	StaticEndpoints     []Endpoint
+	EndpointsCache      string
+	EndpointsCacheTTL   time.Duration
+	EndpointsCacheGrace time.Duration
+	PreferredEndpoint   string
+	PreferredRegion     string
+	Resolver            Resolver
+	ProbeSamples        int
+	ProbeTimeout        time.Duration
+	FailoverThreshold   int
+	FailoverInterval    time.Duration
+	FailoverCooloff     time.Duration
+	MigrationInterval   time.Duration
+	MigrationMargin     float64
+	ReconnectJitter     time.Duration
+	SavedNameServers    []string
+	NebulaDownloadURL   string
+	NebulaManifestURL   string
+	NebulaSHA256        string
+	NebulaSigningKey    string
+	DNSBackend          string
+	Helper              HelperClient
+	HelperUser          string
+	NebulaStopTimeout   time.Duration
+	NebulaMaxRestarts   int
+	Connected           bool
+	Events              *EventHub
+	State               *StateMachine
 }
 
 // Api interface for Protobuf service
 type Api struct {
 }
 
+// connecting is the connect in progress, which can be cancelled
+var connecting struct {
+	sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// runConnect establishes the tunnel, which can be aborted by CancelConnecting
+func runConnect(ctx context.Context, in *Request) error {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	// Do not take over cancelling of the connect in progress.
+	connecting.Lock()
+	if connecting.done != nil {
+		connecting.Unlock()
+		cancel()
+		return alreadyConnected()
+	}
+	connecting.cancel = cancel
+	connecting.done = done
+	connecting.Unlock()
+
+	defer func() {
+		connecting.Lock()
+		if connecting.done == done {
+			connecting.cancel = nil
+			connecting.done = nil
+		}
+		connecting.Unlock()
+		cancel()
+		close(done)
+	}()
+
+	return startApi(ctx, in)
+}
+
+// CancelConnecting aborts the connect in progress, false is returned if there was none
+func CancelConnecting() bool {
+	connecting.Lock()
+	cancel, done := connecting.cancel, connecting.done
+	connecting.Unlock()
+
+	if cancel == nil {
+		return false
+	}
+
+	cancel()
+	<-done
+
+	return true
+}
+
 // StopApi handled for disconnect and quit. Or even nerf-api crash interruption.
 func StopApi() {
-	Cfg.Logger.Debug("disconnect", zap.String("Login", Cfg.Login))
+	CancelConnecting()
+
+	// Wait for reconnects and migrations in progress, they change the tunnel too.
+	reconnectMutex.Lock()
+	defer reconnectMutex.Unlock()
+
+	if err := Cfg.State.Transition(Status_DISCONNECTING, nil); err != nil {
+		Cfg.Logger.Debug("not disconnecting", zap.Error(err))
+		return
+	}
+
+	teardown()
+
+	_ = Cfg.State.Transition(Status_DISCONNECTED, nil)
+}
+
+// teardown stops Nebula, reverts DNS and notifies nerf-server about disconnection
+func teardown() {
+	session := Cfg.State.Session()
+
+	ctx, span := StartSpan(
+		WithRequestID(context.Background()),
+		"disconnect",
+		EndpointAttribute(&session.Endpoint),
+		LoginAttribute(session.Login),
+	)
+	defer span.End()
+
+	logger := Cfg.Logger.With(TraceFields(ctx)...)
+
+	logger.Debug("disconnect", zap.String("Login", session.Login))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	stopWatching()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	if Cfg.NebulaPid == nil {
+	if session.Nebula == nil {
 		return
 	}
 
-	conn, err := grpc.DialContext(ctx, Cfg.CurrentEndpoint.RemoteHost+":9000", grpc.WithInsecure())
+	conn, err := grpc.DialContext(ctx, session.Endpoint.Address(), GRPCDialOptions()...)
 	if err != nil {
-		Cfg.Logger.Fatal(
+		logger.Error(
 			"can't connect to gRPC server",
 			zap.Error(err),
 		)
+	} else {
+		defer conn.Close()
+		client := NewServerClient(conn)
+
+		_, err = client.Disconnect(ctx, &Notify{Login: session.Login})
+		if err != nil {
+			logger.Error(
+				"disconnect",
+				zap.String("Login", session.Login),
+			)
+		}
 	}
 
-	defer conn.Close()
-	client := NewServerClient(conn)
+	if err = helperRevertNameServers(&session.Endpoint); err != nil {
+		logger.Error("can't revert name servers", zap.Error(err))
+	}
+
+	if err = session.Nebula.Stop(Cfg.NebulaStopTimeout); err != nil {
+		logger.Error("can't stop Nebula", zap.Error(err))
+	}
 
-	_, err = client.Disconnect(ctx, &Notify{Login: Cfg.Login})
+	if err = helperDeleteRoute(&session.Endpoint); err != nil {
+		logger.Error("can't delete a static route for gRPC server", zap.Error(err))
+	}
+
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.Nebula = nil
+		s.Endpoint = Endpoint{}
+		s.ClientIP = ""
+	})
+}
+
+// startApi establishes the tunnel via the best endpoint
+func startApi(ctx context.Context, in *Request) (err error) {
+	logger := Cfg.Logger.With(TraceFields(ctx)...)
+
+	if err := Cfg.State.TransitionSession(Status_DISCOVERING, func(_ *Status, s *Session) {
+		bindSession(ctx)
+		if in != nil {
+			s.Login = in.Login
+			s.Token = in.Token
+			Cfg.PreferredEndpoint = in.Endpoint
+			Cfg.PreferredRegion = in.Region
+		}
+	}); err != nil {
+		return alreadyConnected()
+	}
+
+	// Do not leave a half-established tunnel behind.
+	routeAdded := false
+	nameServersSet := false
+	defer func() {
+		if err == nil {
+			return
+		}
+		if ctx.Err() != nil {
+			err = NewError(codes.Canceled, ReasonCanceled, "Connecting was cancelled", nil)
+		}
+		logger.Error("can't connect", zap.Error(err))
+		current := Cfg.State.Session().Endpoint
+		if nameServersSet {
+			if err := helperRevertNameServers(&current); err != nil {
+				logger.Error("can't revert name servers", zap.Error(err))
+			}
+		}
+		if routeAdded {
+			if err := helperDeleteRoute(&current); err != nil {
+				logger.Error("can't delete a static route for gRPC server", zap.Error(err))
+			}
+		}
+		Cfg.State.UpdateSession(func(s *Session) {
+			s.Endpoint = Endpoint{}
+			s.ClientIP = ""
+		})
+		Cfg.State.Fail(err)
+	}()
+
+	// nextState moves on unless connecting is cancelled or interrupted by another transition
+	nextState := func(to Status_State, update func(*Status)) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := Cfg.State.Transition(to, update); err != nil {
+			return interrupted(err)
+		}
+		return nil
+	}
+
+	e, err := SelectEndpoint(ctx)
 	if err != nil {
-		Cfg.Logger.Error(
-			"disconnect",
-			zap.String("Login", Cfg.Login),
-		)
+		return NewError(codes.Unavailable, ReasonNoEndpoints, "Can't discover endpoints", map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if e.RemoteHost == "" {
+		message := "No reachable endpoints found"
+		if Cfg.PreferredEndpoint != "" || Cfg.PreferredRegion != "" {
+			message = "No reachable endpoints found matching the selected endpoint or region"
+		}
+		return NewError(codes.Unavailable, ReasonNoEndpoints, message, map[string]string{
+			"endpoint": Cfg.PreferredEndpoint,
+			"region":   Cfg.PreferredRegion,
+		})
+	}
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.Endpoint = e
+	})
+
+	if err = nextState(Status_AUTHENTICATING, func(s *Status) {
+		s.RemoteHost = e.RemoteHost
+		s.Description = e.Description
+		s.RemoteIP = e.RemoteIP
+	}); err != nil {
+		return err
+	}
+
+	_, span := StartSpan(ctx, "route", EndpointAttribute(&e))
+	err = helperAddRoute(&e)
+	span.End()
+	routeAdded = err == nil
+	if err != nil {
+		return NewError(codes.Internal, ReasonRouteFailed, "Can't create a route to "+e.Description,
+			map[string]string{
+				"destination": e.RemoteIP,
+				"error":       err.Error(),
+			})
+	}
+
+	logger.Debug("authorized", zap.String("login", Cfg.State.Session().Login))
+
+	response, err := requestConfig(ctx, &e)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("connected to LightHouse",
+		zap.String("ClientIP", response.ClientIP),
+		zap.String("LightHouseIP", response.LightHouseIP),
+		zap.Strings("Teams", response.Teams))
+
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.ClientIP = response.ClientIP
+	})
+
+	if err = nextState(Status_CONFIGURING, func(s *Status) {
+		s.ClientIP = response.ClientIP
+		s.Teams = response.Teams
+	}); err != nil {
+		return err
 	}
-	if err = NebulaSetNameServers(Cfg.CurrentEndpoint, Cfg.SavedNameServers, false); err != nil {
-		Cfg.Logger.Fatal("can't revert name servers", zap.Error(err))
+
+	if err = nebulaServerUpgrade(ctx, response.NebulaManifest); err != nil {
+		return NewError(codes.FailedPrecondition, ReasonNebulaFailed,
+			fmt.Sprintf("Can't upgrade Nebula to version %s required by the server", response.NebulaManifest.MinVersion),
+			map[string]string{
+				"error": err.Error(),
+			})
+	}
+
+	_, span = StartSpan(ctx, "configure", EndpointAttribute(&e))
+	err = configureNebula(&e, response)
+	span.End()
+	if err != nil {
+		return err
+	}
+	nameServersSet = true
+
+	if err = nextState(Status_STARTING_NEBULA, nil); err != nil {
+		return err
+	}
+
+	_, span = StartSpan(ctx, "nebula", EndpointAttribute(&e))
+	process, err := NebulaStart(response.Config)
+	span.End()
+	if err != nil {
+		return NewError(codes.Internal, ReasonNebulaFailed, "Can't start Nebula", map[string]string{
+			"error": err.Error(),
+		})
 	}
 
-	if err = syscall.Kill(*Cfg.NebulaPid, syscall.SIGKILL); err != nil {
-		Cfg.Logger.Fatal("can't stop Nebula", zap.Error(err))
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.Nebula = process
+	})
+
+	if err = nextState(Status_CONNECTED, nil); err != nil {
+		if err := process.Stop(Cfg.NebulaStopTimeout); err != nil {
+			logger.Error("can't stop Nebula", zap.Error(err))
+		}
+		Cfg.State.UpdateSession(func(s *Session) {
+			s.Nebula = nil
+		})
+		return err
 	}
 
-	Cfg.NebulaPid = nil
-	Cfg.CurrentEndpoint = &Endpoint{}
+	startWatching(e)
+
+	return nil
 }
 
-func startApi() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// alreadyConnected is the error of connecting while the tunnel is up or being established
+func alreadyConnected() error {
+	return NewError(codes.FailedPrecondition, ReasonAlreadyConnected, "Already connected or connecting",
+		map[string]string{
+			"state": Cfg.State.State().String(),
+		})
+}
 
-	if Cfg.NebulaPid != nil {
-		Cfg.Logger.Fatal("Nebula instance already running")
+// interrupted wraps the error of a state transition happened during connecting
+func interrupted(err error) error {
+	return NewError(codes.Aborted, ReasonInterrupted, "Connecting was interrupted", map[string]string{
+		"error": err.Error(),
+	})
+}
+
+// configureNebula points DNS to the lighthouse
+func configureNebula(e *Endpoint, response *Response) error {
+	if err := helperSetNameServers(e, []string{response.LightHouseIP}, true); err != nil {
+		return NewError(codes.Internal, ReasonDNSFailed, "Can't set DNS servers", map[string]string{
+			"error": err.Error(),
+		})
 	}
 
-	e := GetFastestEndpoint()
-	Cfg.CurrentEndpoint = &e
+	return nil
+}
+
+// nebulaFailed tears down the tunnel when Nebula can't be kept running
+func nebulaFailed(p *NebulaProcess, err error) {
+	reconnectMutex.Lock()
+	defer reconnectMutex.Unlock()
 
-	if Cfg.CurrentEndpoint.RemoteHost == "" {
-		Cfg.Logger.Fatal("no available gRPC endpoints found")
+	// Already disconnected or switched to another Nebula instance.
+	if Cfg.State.Session().Nebula != p {
+		return
 	}
 
-	if err := NebulaAddLightHouseStaticRoute(Cfg.CurrentEndpoint); err != nil {
-		Cfg.Logger.Fatal(
-			"can't create route",
-			zap.String("destination", e.RemoteIP),
-			zap.Error(err),
-		)
+	if err := Cfg.State.Transition(Status_DISCONNECTING, nil); err != nil {
+		Cfg.Logger.Debug("not disconnecting", zap.Error(err))
+		return
+	}
+
+	teardown()
+
+	Cfg.State.Fail(NewError(codes.Internal, ReasonNebulaFailed, "Nebula stopped unexpectedly", map[string]string{
+		"error": err.Error(),
+	}))
+}
+
+// startWatching starts watchers of the endpoint
+func startWatching(e Endpoint) {
+	watchCtx, stop := context.WithCancel(context.Background())
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.stopWatching = stop
+	})
+	go watchEndpoint(watchCtx, e)
+	go checkEndpoint(watchCtx, e)
+	go watchEvents(watchCtx, e)
+	if Cfg.MigrationInterval > 0 {
+		go watchBetterEndpoint(watchCtx, e)
 	}
+}
 
-	Cfg.Logger.Debug("authorized", zap.String("login", Cfg.Login))
+// stopWatching stops the watchers started by startWatching, if any
+func stopWatching() {
+	var stop context.CancelFunc
+	Cfg.State.UpdateSession(func(s *Session) {
+		stop, s.stopWatching = s.stopWatching, nil
+	})
 
-	conn, err := grpc.DialContext(ctx, Cfg.CurrentEndpoint.RemoteHost+":9000", grpc.WithInsecure())
+	if stop != nil {
+		stop()
+	}
+}
+
+// watchEndpoint reconnects via another endpoint when nerf-server is drained
+func watchEndpoint(ctx context.Context, e Endpoint) {
+	conn, err := grpc.DialContext(ctx, e.Address(), GRPCDialOptions()...)
 	if err != nil {
-		Cfg.Logger.Fatal(
-			"can't create connection to gRPC server",
-			zap.Error(err),
-			zap.String("remoteHost", Cfg.CurrentEndpoint.RemoteHost),
-			zap.String("description", Cfg.CurrentEndpoint.Description),
-		)
+		Cfg.Logger.Error("can't watch endpoint", zap.String("RemoteHost", e.RemoteHost), zap.Error(err))
+		return
 	}
+	defer conn.Close()
 
+	stream, err := healthpb.NewHealthClient(conn).Watch(
+		ctx,
+		&healthpb.HealthCheckRequest{Service: ServerServiceName},
+	)
+	if err != nil {
+		Cfg.Logger.Error("can't watch endpoint", zap.String("RemoteHost", e.RemoteHost), zap.Error(err))
+		return
+	}
+
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			// Older servers do not implement health checking.
+			if ctx.Err() == nil && status.Code(err) != codes.Unimplemented {
+				Cfg.Logger.Debug("stopped watching endpoint",
+					zap.String("RemoteHost", e.RemoteHost),
+					zap.Error(err))
+			}
+			return
+		}
+		if response.Status != healthpb.HealthCheckResponse_SERVING {
+			break
+		}
+	}
+
+	// The cached probe results still rank the draining endpoint the best.
+	MarkEndpointBad(&e)
+	reconnect(ctx, e, "endpoint is going away")
+}
+
+// checkEndpoint reconnects via another endpoint after Cfg.FailoverThreshold failed checks
+func checkEndpoint(ctx context.Context, e Endpoint) {
+	conn, err := grpc.DialContext(ctx, e.Address(), GRPCDialOptions()...)
+	if err != nil {
+		Cfg.Logger.Error("can't check endpoint", zap.String("RemoteHost", e.RemoteHost), zap.Error(err))
+		return
+	}
 	defer conn.Close()
 
 	client := NewServerClient(conn)
+	ticker := time.NewTicker(Cfg.FailoverInterval)
+	defer ticker.Stop()
 
-	request := &Request{Token: Cfg.Token, Login: Cfg.Login}
-	response, err := client.Connect(ctx, request)
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, Cfg.FailoverInterval)
+		err := endpointReady(checkCtx, conn)
+		if err == nil {
+			_, err = client.Ping(checkCtx, &PingRequest{Data: time.Now().UnixNano(), Login: Cfg.State.Session().Login})
+		}
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		failures++
+		Cfg.Logger.Warn("endpoint health check failed",
+			zap.String("RemoteHost", e.RemoteHost),
+			zap.Int("Failures", failures),
+			zap.Error(err))
+
+		if failures >= Cfg.FailoverThreshold {
+			MarkEndpointBad(&e)
+			reconnect(ctx, e, "endpoint failed health checks")
+			return
+		}
+	}
+}
+
+// watchEvents receives events from nerf-server and forwards them to the GUI
+func watchEvents(ctx context.Context, e Endpoint) {
+	conn, err := grpc.DialContext(ctx, e.Address(), GRPCDialOptions()...)
 	if err != nil {
-		Cfg.Logger.Fatal(
-			"can't connect to gRPC server",
-			zap.Error(err),
-			zap.String("remoteHost", Cfg.CurrentEndpoint.RemoteHost),
-			zap.String("description", Cfg.CurrentEndpoint.Description),
+		Cfg.Logger.Error("can't watch events", zap.String("RemoteHost", e.RemoteHost), zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	session := Cfg.State.Session()
+	stream, err := NewServerClient(conn).WatchEvents(ctx, &Request{Login: session.Login, Token: session.Token})
+	if err != nil {
+		Cfg.Logger.Error("can't watch events", zap.String("RemoteHost", e.RemoteHost), zap.Error(err))
+		return
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				Cfg.Logger.Error("stopped watching events",
+					zap.String("RemoteHost", e.RemoteHost),
+					zap.Error(err))
+			}
+			return
+		}
+
+		Cfg.Logger.Info("received event",
+			zap.String("Type", event.Type.String()),
+			zap.String("Message", event.Message),
+			zap.String("RemoteHost", e.RemoteHost))
+
+		Cfg.Events.Publish("", event)
+
+		switch event.Type {
+		case Event_TEAMS_CHANGED, Event_CONFIG_AVAILABLE:
+			go reconnectWithJitter(ctx, e, event.Type.String())
+		case Event_CERTIFICATE_REVOKED:
+			go reconnect(ctx, e, event.Type.String())
+		case Event_FORCED_DISCONNECT:
+			go StopApi()
+		}
+	}
+}
+
+// reconnectWithJitter reconnects after a random delay up to Cfg.ReconnectJitter
+func reconnectWithJitter(ctx context.Context, e Endpoint, reason string) {
+	if Cfg.ReconnectJitter > 0 {
+		delay := time.Duration(rand.Int63n(int64(Cfg.ReconnectJitter)))
+		Cfg.Logger.Debug("reconnecting later",
+			zap.String("Reason", reason),
+			zap.Duration("Delay", delay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+
+	reconnect(ctx, e, reason)
+}
+
+// reconnectMutex serializes reconnects, migrations and disconnects
+var reconnectMutex sync.Mutex
+
+// reconnect re-establishes the tunnel via the next-best endpoint
+func reconnect(ctx context.Context, e Endpoint, reason string) {
+	reconnectMutex.Lock()
+	defer reconnectMutex.Unlock()
+
+	// Already reconnected or disconnected by someone else.
+	if ctx.Err() != nil {
+		return
+	}
+
+	ctx, span := StartSpan(
+		WithRequestID(context.Background()),
+		"reconnect",
+		EndpointAttribute(&e),
+		LoginAttribute(Cfg.State.Session().Login),
+	)
+	defer span.End()
+
+	logger := Cfg.Logger.With(TraceFields(ctx)...)
+
+	if err := Cfg.State.Transition(Status_RECONNECTING, nil); err != nil {
+		logger.Debug("not reconnecting", zap.Error(err))
+		return
+	}
+
+	logger.Info("reconnecting",
+		zap.String("Reason", reason),
+		zap.String("RemoteHost", e.RemoteHost),
+		zap.String("Description", e.Description))
+
+	teardown()
+	if err := runConnect(ctx, nil); err != nil {
+		return
+	}
+
+	if current := Cfg.State.Session().Endpoint; current.RemoteHost != e.RemoteHost {
+		event := NewEvent(
+			Event_ENDPOINT_SWITCHED,
+			"Switched to "+current.Description+" ("+reason+")",
 		)
+		event.RemoteIP = current.RemoteIP
+		Cfg.Events.Publish("", event)
 	}
+}
 
-	Cfg.Logger.Debug("connected to LightHouse",
-		zap.String("ClientIP", response.ClientIP),
-		zap.String("LightHouseIP", response.LightHouseIP),
-		zap.Strings("Teams", response.Teams))
+// watchBetterEndpoint migrates to a faster endpoint every Cfg.MigrationInterval
+func watchBetterEndpoint(ctx context.Context, e Endpoint) {
+	ticker := time.NewTicker(Cfg.MigrationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := getVPNEndpoints(ctx); err != nil {
+			if ctx.Err() == nil {
+				Cfg.Logger.Warn("can't re-probe endpoints", zap.Error(err))
+			}
+			continue
+		}
+
+		candidate, ok := betterEndpoint(&e)
+		if !ok {
+			continue
+		}
+
+		if migrate(ctx, e, candidate) {
+			return
+		}
+	}
+}
+
+// betterEndpoint returns the endpoint beating the current one by Cfg.MigrationMargin
+func betterEndpoint(current *Endpoint) (Endpoint, bool) {
+	currentLatency, ok := smoothedLatency(current)
+	if !ok {
+		// Unreachable current endpoint is handled by checkEndpoint.
+		return Endpoint{}, false
+	}
+
+	var best Endpoint
+	bestLatency := math.MaxFloat64
+
+	for _, e := range ProbedEndpoints() {
+		if e.RemoteHost == current.RemoteHost || e.Priority > current.Priority ||
+			endpointBad(&e) || !endpointPreferred(&e) {
+			continue
+		}
+		latency, ok := smoothedLatency(&e)
+		if ok && latency < bestLatency {
+			best = e
+			bestLatency = latency
+		}
+	}
+
+	if best.RemoteHost == "" || bestLatency*(1+Cfg.MigrationMargin) >= currentLatency {
+		return Endpoint{}, false
+	}
+
+	return best, true
+}
+
+// migrate switches the tunnel to the candidate endpoint, making before breaking
+func migrate(ctx context.Context, e Endpoint, candidate Endpoint) bool {
+	reconnectMutex.Lock()
+	defer reconnectMutex.Unlock()
+
+	// Already reconnected or disconnected by someone else.
+	if ctx.Err() != nil {
+		return true
+	}
+
+	ctx, span := StartSpan(
+		WithRequestID(context.Background()),
+		"migrate",
+		EndpointAttribute(&candidate),
+		LoginAttribute(Cfg.State.Session().Login),
+	)
+	defer span.End()
+
+	logger := Cfg.Logger.With(TraceFields(ctx)...)
+
+	logger.Info("migrating to a faster endpoint",
+		zap.String("From", e.RemoteHost),
+		zap.String("To", candidate.RemoteHost),
+		zap.String("Description", candidate.Description))
+
+	if err := helperAddRoute(&candidate); err != nil {
+		logger.Error("can't create route",
+			zap.String("destination", candidate.RemoteIP),
+			zap.Error(err))
+		return false
+	}
+
+	// The route towards the candidate is not needed if it fails to provide a config.
+	deleteCandidateRoute := func() {
+		if candidate.RemoteIP == e.RemoteIP {
+			return
+		}
+		if err := helperDeleteRoute(&candidate); err != nil {
+			logger.Error("can't delete a static route for gRPC server", zap.Error(err))
+		}
+	}
 
-	Cfg.ClientIP = response.ClientIP
+	response, err := requestConfig(ctx, &candidate)
+	if err != nil {
+		logger.Error("can't migrate", zap.Error(err))
+		deleteCandidateRoute()
+		return false
+	}
+
+	if err := nebulaServerUpgrade(ctx, response.NebulaManifest); err != nil {
+		logger.Error("can't migrate", zap.Error(err))
+		deleteCandidateRoute()
+		return false
+	}
+
+	// Break: the new config is obtained, stop using the current endpoint.
+	stopWatching()
+
+	disconnectCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	conn, err := grpc.DialContext(disconnectCtx, e.Address(), GRPCDialOptions()...)
+	if err == nil {
+		_, err = NewServerClient(conn).Disconnect(disconnectCtx, &Notify{Login: Cfg.State.Session().Login})
+		conn.Close()
+	}
+	cancel()
+	if err != nil {
+		logger.Debug("can't notify the previous endpoint about disconnect",
+			zap.String("RemoteHost", e.RemoteHost),
+			zap.Error(err))
+	}
 
-	out, err := os.Create(path.Join(NebulaDir(), "config.yml"))
+	if err := Cfg.State.Session().Nebula.Stop(Cfg.NebulaStopTimeout); err != nil {
+		logger.Error("can't stop Nebula", zap.Error(err))
+	}
+
+	if e.RemoteIP != candidate.RemoteIP {
+		if err := helperDeleteRoute(&e); err != nil {
+			logger.Error("can't delete a static route for gRPC server", zap.Error(err))
+		}
+	}
+
+	process, err := NebulaStart(response.Config)
 	if err != nil {
-		Cfg.Logger.Fatal("can't create Nebula config", zap.Error(err))
+		logger.Error("can't start Nebula client", zap.Error(err))
+		if err := helperRevertNameServers(&e); err != nil {
+			logger.Error("can't revert name servers", zap.Error(err))
+		}
+		// The route of the previous endpoint is gone too, even if it's the same one.
+		if err := helperDeleteRoute(&candidate); err != nil {
+			logger.Error("can't delete a static route for gRPC server", zap.Error(err))
+		}
+		Cfg.State.UpdateSession(func(s *Session) {
+			s.Nebula = nil
+			s.Endpoint = Endpoint{}
+			s.ClientIP = ""
+		})
+		Cfg.State.Fail(NewError(codes.Internal, ReasonNebulaFailed, "Can't start Nebula", map[string]string{
+			"error": err.Error(),
+		}))
+		return true
 	}
 
-	if _, err := out.WriteString(response.Config); err != nil {
-		Cfg.Logger.Fatal("can't write Nebula config", zap.Error(err))
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.Nebula = process
+		s.Endpoint = candidate
+		s.ClientIP = response.ClientIP
+	})
+
+	if err := helperSetNameServers(&candidate, []string{response.LightHouseIP}, false); err != nil {
+		logger.Error("can't set custom DNS servers", zap.Error(err))
 	}
-	defer out.Close()
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt)
+	Cfg.State.Update(func(s *Status) {
+		s.RemoteHost = candidate.RemoteHost
+		s.Description = candidate.Description
+		s.RemoteIP = candidate.RemoteIP
+		s.ClientIP = response.ClientIP
+		s.Teams = response.Teams
+	})
+
+	startWatching(candidate)
 
-	if err := NebulaSetNameServers(&e, []string{response.LightHouseIP}, true); err != nil {
-		Cfg.Logger.Fatal("can't set custom DNS servers", zap.Error(err))
+	event := NewEvent(
+		Event_ENDPOINT_SWITCHED,
+		"Switched to "+candidate.Description+" (faster endpoint)",
+	)
+	event.RemoteIP = candidate.RemoteIP
+	Cfg.Events.Publish("", event)
+
+	return true
+}
+
+// requestConfig retrieves config.yml for Nebula from nerf-server
+func requestConfig(ctx context.Context, e *Endpoint) (*Response, error) {
+	session := Cfg.State.Session()
+
+	ctx, span := StartSpan(ctx, "authenticate", EndpointAttribute(e), LoginAttribute(session.Login))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	unavailable := func(err error) error {
+		return NewError(codes.Unavailable, ReasonServerUnavailable, "Can't reach "+e.Description,
+			map[string]string{
+				"remoteHost": e.RemoteHost,
+				"error":      err.Error(),
+			})
 	}
 
-	pid, err := NebulaStart()
+	conn, err := grpc.DialContext(ctx, e.Address(), GRPCDialOptions()...)
 	if err != nil {
-		Cfg.Logger.Fatal("can't start Nebula client", zap.Error(err))
+		return nil, unavailable(err)
 	}
 
-	Cfg.NebulaPid = &pid
+	defer conn.Close()
 
-	<-done
+	client := NewServerClient(conn)
 
-	StopApi()
+	request := &Request{Token: session.Token, Login: session.Login}
+	response, err := client.Connect(ctx, request)
+	if err != nil {
+		// Errors of nerf-server are passed to the GUI as is.
+		if ErrorReason(err) != "" {
+			return nil, err
+		}
+		return nil, unavailable(err)
+	}
+
+	return response, nil
 }
 
 func (s *Api) Ping(ctx context.Context, in *PingRequest) (*PingResponse, error) {
@@ -181,33 +895,150 @@ func (s *Api) Ping(ctx context.Context, in *PingRequest) (*PingResponse, error)
 
 // Connect used to notify API about initiated connect
 func (s *Api) Connect(ctx context.Context, in *Request) (*ApiResponse, error) {
-	Cfg.Login = in.Login
-	Cfg.Token = in.Token
+	if err := checkSessionOwner(ctx); err != nil {
+		return nil, err
+	}
 
-	go startApi()
+	ctx, span := StartSpan(ctx, "connect", LoginAttribute(in.Login))
+	defer span.End()
 
-	// Wait for the ClientIP to be assigned from the server and endpoint's IP.
-	for {
-		if len(Cfg.ClientIP) > 0 && len(Cfg.CurrentEndpoint.RemoteIP) > 0 {
-			break
+	// Goroutines started by startApi outlive this request, thus keep only the trace and request IDs,
+	// and the caller to bind the session to.
+	connectCtx := context.WithValue(
+		trace.ContextWithSpan(context.Background(), span),
+		requestIDContextKey{},
+		RequestID(ctx),
+	)
+	if p, ok := peer.FromContext(ctx); ok {
+		connectCtx = peer.NewContext(connectCtx, p)
+	}
+	connectCtx, cancelConnect := context.WithCancel(connectCtx)
+	defer cancelConnect()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runConnect(connectCtx, in)
+	}()
+
+	// If the caller gives up (e.g. the GUI quits), the connect is cancelled.
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
 		}
+	case <-ctx.Done():
+		cancelConnect()
+		<-done
+		return nil, status.FromContextError(ctx.Err()).Err()
 	}
 
+	session := Cfg.State.Session()
+
 	return &ApiResponse{
-		ClientIP: Cfg.ClientIP,
-		RemoteIP: Cfg.CurrentEndpoint.RemoteIP,
+		ClientIP: session.ClientIP,
+		RemoteIP: session.Endpoint.RemoteIP,
 	}, nil
 }
 
+// WatchEvents streams events received from nerf-server to the GUI of the session owner
+func (s *Api) WatchEvents(in *Notify, stream Api_WatchEventsServer) error {
+	events := Cfg.Events.Subscribe("")
+	defer Cfg.Events.Unsubscribe(events)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if !ownsSession(stream.Context()) {
+				continue
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// GetEndpoints returns the endpoints with their probe results
+func (s *Api) GetEndpoints(ctx context.Context, in *Notify) (*EndpointsResponse, error) {
+	endpoints := ProbedEndpoints()
+	if len(endpoints) == 0 {
+		if err := getVPNEndpoints(ctx); err != nil {
+			return nil, err
+		}
+		endpoints = ProbedEndpoints()
+	}
+
+	current := visibleStatus(ctx, Cfg.State.Status())
+	response := &EndpointsResponse{}
+	for _, e := range endpoints {
+		info := &EndpointInfo{
+			Description: e.Description,
+			RemoteHost:  e.RemoteHost,
+			RemoteIP:    e.RemoteIP,
+			Port:        uint32(e.Port),
+			Priority:    uint32(e.Priority),
+			Weight:      uint32(e.Weight),
+			Reachable:   e.Latency != math.MaxInt64,
+			Current:     e.RemoteHost == current.RemoteHost && current.State == Status_CONNECTED,
+		}
+		if info.Reachable {
+			info.Latency = e.Latency
+			info.Jitter = e.Jitter
+		}
+		response.Endpoints = append(response.Endpoints, info)
+	}
+
+	return response, nil
+}
+
+// GetStatus returns the current connection status
+func (s *Api) GetStatus(ctx context.Context, in *Notify) (*Status, error) {
+	return visibleStatus(ctx, Cfg.State.Status()), nil
+}
+
+// WatchStatus streams the current connection status and all its changes
+func (s *Api) WatchStatus(in *Notify, stream Api_WatchStatusServer) error {
+	statuses := Cfg.State.Subscribe()
+	defer Cfg.State.Unsubscribe(statuses)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case current := <-statuses:
+			if err := stream.Send(visibleStatus(stream.Context(), current)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// CancelConnect aborts the connect in progress
+func (s *Api) CancelConnect(ctx context.Context, in *Notify) (*empty.Empty, error) {
+	if err := checkSessionOwner(ctx); err != nil {
+		return nil, err
+	}
+
+	if !CancelConnecting() {
+		return nil, NewError(codes.FailedPrecondition, ReasonNotConnecting, "Not connecting", map[string]string{
+			"state": Cfg.State.State().String(),
+		})
+	}
+
+	return &empty.Empty{}, nil
+}
+
 // Disconnect used to notify API about initiated disconnect
 func (s *Api) Disconnect(ctx context.Context, in *Notify) (*empty.Empty, error) {
-	var err error
-
-	Cfg.Login = in.Login
+	if err := checkSessionOwner(ctx); err != nil {
+		return nil, err
+	}
 
 	go StopApi()
 
-	return &empty.Empty{}, err
+	return &empty.Empty{}, nil
 }
 
 // NewConfig initializes Config
@@ -220,14 +1051,39 @@ func NewConfig() Config {
 			Scopes:       []string{"user:email"},
 			Endpoint:     githuboauth.Endpoint,
 		},
-		Token:            "",
-		ListenAddr:       "127.0.0.1:1337",
-		Login:            "",
-		Endpoints:        map[string]Endpoint{},
-		CurrentEndpoint:  &Endpoint{},
-		SavedNameServers: []string{},
-		NebulaPid:        nil,
-		Connected:        false,
-		ClientIP:         "",
+		DeviceAuthURL:       "https://github.com/login/device/code",
+		Token:               "",
+		ListenAddr:          "127.0.0.1:0",
+		AuthTimeout:         5 * time.Minute,
+		ApiGroup:            "nerf",
+		Login:               "",
+		Endpoints:           map[string]Endpoint{},
+		StaticEndpoints:     []Endpoint{},
+		EndpointsCache:      path.Join(NebulaDir(), "endpoints.json"),
+		EndpointsCacheTTL:   5 * time.Minute,
+		EndpointsCacheGrace: 24 * time.Hour,
+		PreferredEndpoint:   "",
+		PreferredRegion:     "",
+		Resolver:            net.DefaultResolver,
+		ProbeSamples:        5,
+		ProbeTimeout:        5 * time.Second,
+		FailoverThreshold:   3,
+		FailoverInterval:    5 * time.Second,
+		FailoverCooloff:     5 * time.Minute,
+		MigrationInterval:   5 * time.Minute,
+		MigrationMargin:     0.3,
+		ReconnectJitter:     30 * time.Second,
+		SavedNameServers:    []string{},
+		NebulaDownloadURL:   nebulaDownloadLink(),
+		NebulaManifestURL:   NebulaManifestURL,
+		NebulaSHA256:        NebulaSHA256,
+		NebulaSigningKey:    NebulaSigningKey,
+		DNSBackend:          "auto",
+		HelperUser:          "nerf-api",
+		NebulaStopTimeout:   10 * time.Second,
+		NebulaMaxRestarts:   5,
+		Connected:           false,
+		Events:              NewEventHub(),
+		State:               NewStateMachine(),
 	}
 }
@@ -0,0 +1,181 @@
+package nerf
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockingResolver never answers, so connecting stays in discovery until cancelled
+type blockingResolver struct{}
+
+func (blockingResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	<-ctx.Done()
+	return "", nil, ctx.Err()
+}
+
+func (blockingResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// connectDiscovering starts Api.Connect blocked in discovery and returns its result
+func connectDiscovering(t *testing.T, ctx context.Context) chan error {
+	state, resolver, cache := Cfg.State, Cfg.Resolver, Cfg.EndpointsCache
+	t.Cleanup(func() {
+		Cfg.State, Cfg.Resolver, Cfg.EndpointsCache = state, resolver, cache
+	})
+
+	Cfg.State = NewStateMachine()
+	Cfg.Resolver = blockingResolver{}
+	Cfg.EndpointsCache = ""
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := (&Api{}).Connect(ctx, &Request{Login: "alice"})
+		done <- err
+	}()
+
+	for Cfg.State.State() != Status_DISCOVERING {
+		time.Sleep(time.Millisecond)
+	}
+
+	return done
+}
+
+func TestCancelConnect(t *testing.T) {
+	done := connectDiscovering(t, context.Background())
+
+	if _, err := (&Api{}).CancelConnect(context.Background(), &Notify{Login: "alice"}); err != nil {
+		t.Fatalf("CancelConnect() error = %v", err)
+	}
+
+	// CancelConnect returns once the connect is over.
+	if got := Cfg.State.Status(); got.State != Status_DISCONNECTED || got.ErrorReason != ReasonCanceled {
+		t.Errorf("Status() = %v, want disconnected with %s", got, ReasonCanceled)
+	}
+
+	select {
+	case err := <-done:
+		if reason := ErrorReason(err); reason != ReasonCanceled {
+			t.Errorf("Connect() error = %v, want %s", err, ReasonCanceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Connect() did not return after CancelConnect()")
+	}
+
+	_, err := (&Api{}).CancelConnect(context.Background(), &Notify{Login: "alice"})
+	if reason := ErrorReason(err); reason != ReasonNotConnecting {
+		t.Errorf("CancelConnect() without a connect in progress error = %v, want %s", err, ReasonNotConnecting)
+	}
+}
+
+func TestConnectCallerGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := connectDiscovering(t, ctx)
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.Canceled {
+			t.Errorf("Connect() error = %v, want %s", err, codes.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Connect() did not return after the caller gave up")
+	}
+
+	if got := Cfg.State.State(); got != Status_DISCONNECTED {
+		t.Errorf("State() = %s, want %s", got, Status_DISCONNECTED)
+	}
+	if CancelConnecting() {
+		t.Error("CancelConnecting() found a connect in progress")
+	}
+}
+
+// configServer is nerf-server handing out the config without authenticating
+type configServer struct {
+	UnimplementedServerServer
+}
+
+func (*configServer) Connect(ctx context.Context, in *Request) (*Response, error) {
+	return &Response{Config: "config", ClientIP: "10.0.0.2", LightHouseIP: "10.0.0.1"}, nil
+}
+
+func (*configServer) Disconnect(ctx context.Context, in *Notify) (*empty.Empty, error) {
+	return &empty.Empty{}, nil
+}
+
+// serveConfig starts configServer on the address and returns the endpoint of it
+func serveConfig(t *testing.T, address string) Endpoint {
+	lis, err := net.Listen("tcp", net.JoinHostPort(address, "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	RegisterServerServer(server, &configServer{})
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	return Endpoint{
+		Description: address,
+		RemoteHost:  address,
+		RemoteIP:    address,
+		Port:        uint16(lis.Addr().(*net.TCPAddr).Port),
+	}
+}
+
+func TestMigrateNebulaFails(t *testing.T) {
+	helper := useFakeHelper(t)
+	current, candidate := serveConfig(t, "127.0.0.1"), serveConfig(t, "127.0.0.2")
+
+	state := Cfg.State
+	defer func() {
+		Cfg.State = state
+	}()
+	Cfg.State = stateMachineIn(t, Status_CONNECTED)
+
+	process, err := NebulaStart("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := helperAddRoute(&current); err != nil {
+		t.Fatal(err)
+	}
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.Login = "alice"
+		s.Endpoint = current
+		s.Nebula = process
+	})
+
+	helper.mutex.Lock()
+	helper.startErr = status.Error(codes.Internal, "can't start Nebula")
+	helper.mutex.Unlock()
+
+	if !migrate(context.Background(), current, candidate) {
+		t.Fatal("migrate() kept the current endpoint after the break")
+	}
+
+	if got := Cfg.State.Status(); got.State != Status_DISCONNECTED || got.ErrorReason != ReasonNebulaFailed {
+		t.Errorf("status = %s (%s), want DISCONNECTED (%s)", got.State, got.ErrorReason, ReasonNebulaFailed)
+	}
+	helper.mutex.Lock()
+	defer helper.mutex.Unlock()
+	if len(helper.routes) != 0 {
+		t.Errorf("routes left after failed migration: %v", helper.routes)
+	}
+}
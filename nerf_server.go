@@ -4,17 +4,27 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/google/go-github/github"
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 // ServerCfg is a global configuration for Nerf server
 var ServerCfg ServerConfig
 
+// nebulaManifestMutex guards ServerCfg.NebulaManifest reloaded on SIGHUP
+var nebulaManifestMutex sync.RWMutex
+
 // OauthMasterToken compile-time derived from -X github.com/ton31337/nerf.OauthMasterToken
 var OauthMasterToken string
 
@@ -24,11 +34,15 @@ var OauthOrganization string
 
 // ServerConfig struct to store all the relevant data for a server
 type ServerConfig struct {
-	Logger    *zap.Logger
-	Login     string
-	Nebula    *Nebula
-	Teams     *Teams
-	GaidysUrl string
+	Logger         *zap.Logger
+	Login          string
+	Nebula         *Nebula
+	NebulaManifest *NebulaManifest
+	Teams          *Teams
+	GaidysUrl      string
+	Health         *health.Server
+	Events         *EventHub
+	draining       int32
 }
 
 // Server interface for Protobuf service
@@ -40,6 +54,7 @@ type Teams struct {
 	Mutex     *NerfMutex
 	Members   map[string][]string
 	UpdatedAt int64
+	Synced    bool
 }
 
 // Ping get timestamp in milliseconds
@@ -57,7 +72,10 @@ func (s *Server) Ping(ctx context.Context, in *PingRequest) (*PingResponse, erro
 func (t *Teams) User(login string) []string {
 	var teams []string
 
-	for team, users := range ServerCfg.Teams.Members {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	for team, users := range t.Members {
 		for _, user := range users {
 			if login == user {
 				teams = append(teams, team)
@@ -70,7 +88,7 @@ func (t *Teams) User(login string) []string {
 
 // SyncTeams sync Github Teams with local cache
 // Scheduled every 10 seconds and updated every hour.
-func (t *Teams) Sync() {
+func (t *Teams) Sync() error {
 	token := &TokenSource{
 		AccessToken: OauthMasterToken,
 	}
@@ -78,29 +96,33 @@ func (t *Teams) Sync() {
 	client := github.NewClient(oclient)
 
 	teamOptions := github.ListOptions{PerPage: 500}
+	members := make(map[string][]string)
 
 	for {
-		teams, respTeams, _ := client.Teams.ListTeams(
+		teams, respTeams, err := client.Teams.ListTeams(
 			context.Background(),
 			OauthOrganization,
 			&teamOptions,
 		)
+		if err != nil {
+			return fmt.Errorf("failed listing teams: %s", err)
+		}
 		for _, team := range teams {
-			ServerCfg.Teams.Members[*team.Name] = make([]string, 0)
+			members[*team.Name] = make([]string, 0)
 			usersOptions := &github.TeamListTeamMembersOptions{
 				ListOptions: github.ListOptions{PerPage: 500},
 			}
 			for {
-				users, respUsers, _ := client.Teams.ListTeamMembers(
+				users, respUsers, err := client.Teams.ListTeamMembers(
 					context.Background(),
 					*team.ID,
 					usersOptions,
 				)
+				if err != nil {
+					return fmt.Errorf("failed listing members of team %s: %s", *team.Name, err)
+				}
 				for _, user := range users {
-					ServerCfg.Teams.Members[*team.Name] = append(
-						ServerCfg.Teams.Members[*team.Name],
-						*user.Login,
-					)
+					members[*team.Name] = append(members[*team.Name], *user.Login)
 				}
 				if respUsers.NextPage == 0 {
 					break
@@ -113,7 +135,96 @@ func (t *Teams) Sync() {
 		}
 		teamOptions.Page = respTeams.NextPage
 	}
-	ServerCfg.Teams.UpdatedAt = time.Now().Unix()
+
+	t.Mutex.Lock()
+	previous := t.Members
+	synced := t.Synced
+	t.Members = members
+	t.UpdatedAt = time.Now().Unix()
+	t.Synced = true
+	t.Mutex.Unlock()
+
+	if synced {
+		notifyTeamsChanged(previous, members)
+	}
+
+	return nil
+}
+
+// IsSynced reports whether Github Teams were synced at least once
+func (t *Teams) IsSynced() bool {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	return t.Synced
+}
+
+// userTeams maps every login to the sorted list of its teams
+func userTeams(members map[string][]string) map[string][]string {
+	users := make(map[string][]string)
+
+	for team, logins := range members {
+		for _, login := range logins {
+			users[login] = append(users[login], team)
+		}
+	}
+
+	for login := range users {
+		sort.Strings(users[login])
+	}
+
+	return users
+}
+
+// notifyTeamsChanged sends events to the users whose team membership changed
+func notifyTeamsChanged(previous map[string][]string, current map[string][]string) {
+	currentUsers := userTeams(current)
+
+	for login, teams := range userTeams(previous) {
+		newTeams, ok := currentUsers[login]
+		if !ok {
+			ServerCfg.Events.Publish(login, NewEvent(
+				Event_FORCED_DISCONNECT,
+				"You are not a member of any team anymore",
+			))
+			continue
+		}
+		if strings.Join(teams, ",") != strings.Join(newTeams, ",") {
+			ServerCfg.Events.Publish(login, NewEvent(
+				Event_TEAMS_CHANGED,
+				"Your teams changed: "+strings.Join(newTeams, ", "),
+			))
+		}
+	}
+}
+
+// SetReady switches the health status of nerf-server to SERVING
+func (s *ServerConfig) SetReady() {
+	s.Health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.Health.SetServingStatus(ServerServiceName, healthpb.HealthCheckResponse_SERVING)
+}
+
+// SetNebulaManifest replaces the manifest sent to clients on connect
+func (s *ServerConfig) SetNebulaManifest(manifest *NebulaManifest) {
+	nebulaManifestMutex.Lock()
+	defer nebulaManifestMutex.Unlock()
+
+	s.NebulaManifest = manifest
+}
+
+// Drain stops accepting new connects and reports NOT_SERVING
+func (s *ServerConfig) Drain() {
+	atomic.StoreInt32(&s.draining, 1)
+	s.Health.Shutdown()
+	s.Events.Publish("", NewEvent(
+		Event_MAINTENANCE_SCHEDULED,
+		"The server is going down for maintenance, reconnecting to another one",
+	))
+}
+
+// IsDraining reports whether Drain was called
+func (s *ServerConfig) IsDraining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
 }
 
 // Disconnect - notify the server about disconnection
@@ -129,63 +240,143 @@ func (s *Server) Disconnect(ctx context.Context, in *Notify) (*empty.Empty, erro
 	return &empty.Empty{}, err
 }
 
+// githubUser validates the token of the request against Github
+func githubUser(ctx context.Context, in *Request) (*github.User, error) {
+	ctx, span := StartSpan(ctx, "github", LoginAttribute(in.Login))
+	defer span.End()
+
+	token := &TokenSource{
+		AccessToken: in.Token,
+	}
+	oclient := oauth2.NewClient(ctx, token)
+	client := github.NewClient(oclient)
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed validate login %s(%s): %s", user, in.Login, err)
+	}
+
+	return user, nil
+}
+
+// WatchEvents - streams events for the user until the client disconnects
+func (s *Server) WatchEvents(in *Request, stream Server_WatchEventsServer) error {
+	if in.Login == "" {
+		return fmt.Errorf("failed gRPC watch events request")
+	}
+
+	user, err := githubUser(stream.Context(), in)
+	if err != nil {
+		return NewError(codes.Unauthenticated, ReasonUnauthenticated,
+			"Can't authenticate you with GitHub, sign in again", map[string]string{
+				"login": in.Login,
+			})
+	}
+
+	ServerCfg.Logger.With(TraceFields(stream.Context())...).Debug("watch events",
+		zap.String("Login", *user.Login))
+
+	events := ServerCfg.Events.Subscribe(*user.Login)
+	defer ServerCfg.Events.Unsubscribe(events)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // Connect - connects to the server which generates config.yml for Nebula
 func (s *Server) Connect(ctx context.Context, in *Request) (*Response, error) {
 	if in.Login == "" {
 		return nil, fmt.Errorf("failed gRPC certificate request")
 	}
 
-	ServerCfg.Logger.Debug("connect", zap.String("Login", in.Login))
+	logger := ServerCfg.Logger.With(TraceFields(ctx)...)
 
-	token := &TokenSource{
-		AccessToken: in.Token,
+	logger.Debug("connect", zap.String("Login", in.Login))
+
+	if !ServerCfg.Teams.IsSynced() {
+		return nil, NewError(codes.Unavailable, ReasonTeamsNotSynced,
+			"The server is starting up, try again later", nil)
 	}
-	oclient := oauth2.NewClient(context.Background(), token)
-	client := github.NewClient(oclient)
-	user, _, err := client.Users.Get(context.Background(), "")
+
+	if ServerCfg.IsDraining() {
+		return nil, NewError(codes.Unavailable, ReasonServerDraining,
+			"The server is going down for maintenance, try again later", nil)
+	}
+
+	user, err := githubUser(ctx, in)
 	if err != nil {
-		return nil, fmt.Errorf("failed validate login %s(%s): %s", user, in.Login, err)
+		return nil, NewError(codes.Unauthenticated, ReasonUnauthenticated,
+			"Can't authenticate you with GitHub, sign in again", map[string]string{
+				"login": in.Login,
+			})
 	}
 
 	userTeams := ServerCfg.Teams.User(*user.Login)
 	if len(userTeams) == 0 {
-		ServerCfg.Logger.Debug("teams not found", zap.String("Login", *user.Login))
-		return nil, fmt.Errorf("no teams founds")
+		logger.Debug("teams not found", zap.String("Login", *user.Login))
+		return nil, NewError(codes.PermissionDenied, ReasonNotTeamMember,
+			"You are not a member of any team", map[string]string{
+				"login": *user.Login,
+			})
 	}
 
 	ServerCfg.Login = *user.Login
-	clientIP, err := NebulaClientIP()
+	clientIP, err := NebulaClientIP(ctx)
 	if err != nil {
-		ServerCfg.Logger.Debug("IP address not found in IPAM",
+		logger.Debug("IP address not found in IPAM",
 			zap.Error(err),
 			zap.String("Login", *user.Login))
-		return nil, fmt.Errorf("no IP address")
+		return nil, NewError(codes.FailedPrecondition, ReasonNoIPAddress,
+			"IPAM has no address for you", map[string]string{
+				"login": *user.Login,
+			})
 	}
 
-	config, err := NebulaGenerateConfig(userTeams)
+	config, err := NebulaGenerateConfig(ctx, userTeams)
 	if err != nil {
-		ServerCfg.Logger.Error(
+		logger.Error(
 			"can't generate config for Nebula",
 			zap.String("Login", *user.Login),
 			zap.Strings("Teams", userTeams),
 			zap.Error(err),
 		)
+		return nil, NewError(codes.Internal, ReasonConfigFailed,
+			"Can't generate Nebula config for you", map[string]string{
+				"login": *user.Login,
+			})
 	}
 
-	ServerCfg.Logger.Debug("teams found",
+	logger.Debug("teams found",
 		zap.String("Login", *user.Login),
 		zap.String("ClientIP", clientIP.IP.String()),
 		zap.Strings("Teams", userTeams))
 
+	nebulaManifestMutex.RLock()
+	nebulaManifest := ServerCfg.NebulaManifest
+	nebulaManifestMutex.RUnlock()
+
 	return &Response{
-		Config:       config,
-		ClientIP:     clientIP.IP.String(),
-		LightHouseIP: ServerCfg.Nebula.LightHouse.NebulaIP,
-		Teams:        userTeams,
+		Config:         config,
+		ClientIP:       clientIP.IP.String(),
+		LightHouseIP:   ServerCfg.Nebula.LightHouse.NebulaIP,
+		Teams:          userTeams,
+		NebulaManifest: nebulaManifest,
 	}, nil
 }
 
 func NewServerConfig() ServerConfig {
+	// Report NOT_SERVING until the first sync of Github Teams is done.
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus(ServerServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+
 	return ServerConfig{
 		Logger: &zap.Logger{},
 		Login:  "",
@@ -196,8 +387,10 @@ func NewServerConfig() ServerConfig {
 		Teams: &Teams{
 			Members:   make(map[string][]string),
 			UpdatedAt: time.Now().Unix() - 24*3600,
-			Mutex:     &NerfMutex{InUse: true},
+			Mutex:     &NerfMutex{},
 		},
 		GaidysUrl: os.Getenv("GAIDYS_URL"),
+		Health:    healthServer,
+		Events:    NewEventHub(),
 	}
 }
@@ -0,0 +1,168 @@
+package nerf
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// useServerConfig sets a fresh ServerCfg for the test
+func useServerConfig(t *testing.T) {
+	previous := ServerCfg
+	ServerCfg = NewServerConfig()
+	ServerCfg.Logger = zap.NewNop()
+	t.Cleanup(func() {
+		ServerCfg = previous
+	})
+}
+
+// healthStatus returns the health status of nerf-server reported for the service
+func healthStatus(t *testing.T, service string) healthpb.HealthCheckResponse_ServingStatus {
+	response, err := ServerCfg.Health.Check(
+		context.Background(),
+		&healthpb.HealthCheckRequest{Service: service},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return response.Status
+}
+
+func TestTeamsUser(t *testing.T) {
+	teams := &Teams{
+		Mutex: &NerfMutex{},
+		Members: map[string][]string{
+			"admins":     {"alice"},
+			"developers": {"alice", "bob"},
+		},
+	}
+
+	tests := []struct {
+		login string
+		want  []string
+	}{
+		{login: "alice", want: []string{"admins", "developers"}},
+		{login: "bob", want: []string{"developers"}},
+		{login: "eve", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.login, func(t *testing.T) {
+			got := teams.User(tt.login)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("User() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerConfigSetReady(t *testing.T) {
+	useServerConfig(t)
+
+	for _, service := range []string{"", ServerServiceName} {
+		if got := healthStatus(t, service); got != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("status of %q before SetReady() = %s, want NOT_SERVING", service, got)
+		}
+	}
+
+	ServerCfg.SetReady()
+
+	for _, service := range []string{"", ServerServiceName} {
+		if got := healthStatus(t, service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("status of %q after SetReady() = %s, want SERVING", service, got)
+		}
+	}
+}
+
+func TestConnectBeforeTeamsSynced(t *testing.T) {
+	useServerConfig(t)
+
+	if ServerCfg.Teams.IsSynced() {
+		t.Fatal("IsSynced() = true before the first sync")
+	}
+
+	server := &Server{}
+	if _, err := server.Connect(context.Background(), &Request{Login: "alice"}); err == nil {
+		t.Error("Connect() before the first sync of teams succeeded")
+	}
+}
+
+func TestEndpointReady(t *testing.T) {
+	tests := []struct {
+		name   string
+		health bool
+		status healthpb.HealthCheckResponse_ServingStatus
+		want   bool
+	}{
+		{name: "serving", health: true, status: healthpb.HealthCheckResponse_SERVING, want: true},
+		{name: "not serving", health: true, status: healthpb.HealthCheckResponse_NOT_SERVING, want: false},
+		{name: "no health checking", health: false, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useServerConfig(t)
+			ServerCfg.Health.SetServingStatus(ServerServiceName, tt.status)
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := grpc.NewServer()
+			if tt.health {
+				healthpb.RegisterHealthServer(server, ServerCfg.Health)
+			}
+			go func() {
+				_ = server.Serve(lis)
+			}()
+			defer server.Stop()
+
+			conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			err = endpointReady(context.Background(), conn)
+			if (err == nil) != tt.want {
+				t.Errorf("endpointReady() error = %v, want ready %t", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerConfigDrain(t *testing.T) {
+	useServerConfig(t)
+	ServerCfg.Teams.Synced = true
+	ServerCfg.SetReady()
+
+	if ServerCfg.IsDraining() {
+		t.Fatal("IsDraining() = true before Drain()")
+	}
+
+	ServerCfg.Drain()
+
+	if !ServerCfg.IsDraining() {
+		t.Error("IsDraining() = false after Drain()")
+	}
+	// SetReady after the drain must not bring the server back.
+	ServerCfg.SetReady()
+	for _, service := range []string{"", ServerServiceName} {
+		if got := healthStatus(t, service); got != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("status of %q after Drain() = %s, want NOT_SERVING", service, got)
+		}
+	}
+
+	server := &Server{}
+	if _, err := server.Connect(context.Background(), &Request{Login: "alice"}); err == nil {
+		t.Error("Connect() to a draining server succeeded")
+	}
+}
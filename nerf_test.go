@@ -0,0 +1,634 @@
+package nerf
+
+import (
+	"context"
+	"math"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+func TestMain(m *testing.M) {
+	Cfg = NewConfig()
+	Cfg.Logger = zap.NewNop()
+	ServerCfg = NewServerConfig()
+	ServerCfg.Logger = zap.NewNop()
+
+	os.Exit(m.Run())
+}
+
+func TestMedianRTT(t *testing.T) {
+	tests := []struct {
+		samples []time.Duration
+		want    time.Duration
+	}{
+		{samples: []time.Duration{10}, want: 10},
+		{samples: []time.Duration{30, 10, 20}, want: 20},
+		{samples: []time.Duration{40, 10, 30, 20}, want: 25},
+		{samples: []time.Duration{10, 10, 500}, want: 10},
+	}
+
+	for _, tt := range tests {
+		if got := medianRTT(tt.samples); got != tt.want {
+			t.Errorf("medianRTT(%v) = %d, want %d", tt.samples, got, tt.want)
+		}
+	}
+}
+
+func TestMedianRTTKeepsSamples(t *testing.T) {
+	samples := []time.Duration{30, 10, 20}
+	medianRTT(samples)
+
+	if samples[0] != 30 || samples[1] != 10 || samples[2] != 20 {
+		t.Errorf("medianRTT() reordered the samples: %v", samples)
+	}
+}
+
+func TestJitterRTT(t *testing.T) {
+	tests := []struct {
+		samples []time.Duration
+		want    time.Duration
+	}{
+		{samples: nil, want: 0},
+		{samples: []time.Duration{10}, want: 0},
+		{samples: []time.Duration{10, 10, 10}, want: 0},
+		{samples: []time.Duration{10, 20, 10}, want: 10},
+		{samples: []time.Duration{10, 40, 30}, want: 20},
+	}
+
+	for _, tt := range tests {
+		if got := jitterRTT(tt.samples); got != tt.want {
+			t.Errorf("jitterRTT(%v) = %d, want %d", tt.samples, got, tt.want)
+		}
+	}
+}
+
+func TestParseStaticEndpoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []Endpoint
+		wantErr bool
+	}{
+		{
+			name:  "host",
+			specs: []string{"vpn.example.com"},
+			want: []Endpoint{
+				{Description: "vpn.example.com", RemoteHost: "vpn.example.com", Port: DefaultServerPort},
+			},
+		},
+		{
+			name:  "port and description",
+			specs: []string{"192.0.2.1:9443=Vilnius", " vpn.example.com=Kaunas "},
+			want: []Endpoint{
+				{Description: "Vilnius", RemoteHost: "192.0.2.1", Port: 9443},
+				{Description: "Kaunas", RemoteHost: "vpn.example.com", Port: DefaultServerPort},
+			},
+		},
+		{
+			name:    "IPv6",
+			specs:   []string{"[2001:db8::1]:9000"},
+			wantErr: true,
+		},
+		{
+			name:  "empty",
+			specs: []string{"", " "},
+			want:  nil,
+		},
+		{
+			name:    "invalid port",
+			specs:   []string{"vpn.example.com:vpn"},
+			wantErr: true,
+		},
+		{
+			name:    "port out of range",
+			specs:   []string{"vpn.example.com:65536"},
+			wantErr: true,
+		},
+		{
+			name:    "no host",
+			specs:   []string{":9000=Vilnius"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStaticEndpoints(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStaticEndpoints() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStaticEndpoints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectWeightedEndpoint(t *testing.T) {
+	tests := []struct {
+		name  string
+		group []Endpoint
+		// want is the expected share of selections of each endpoint
+		want map[string]float64
+	}{
+		{
+			name:  "empty",
+			group: nil,
+			want:  map[string]float64{"": 1},
+		},
+		{
+			name:  "single",
+			group: []Endpoint{{RemoteHost: "a", Weight: 0, Latency: 10}},
+			want:  map[string]float64{"a": 1},
+		},
+		{
+			name: "weights",
+			group: []Endpoint{
+				{RemoteHost: "a", Weight: 90, Latency: 10},
+				{RemoteHost: "b", Weight: 10, Latency: 10},
+			},
+			want: map[string]float64{"a": 0.9, "b": 0.1},
+		},
+		{
+			name: "all zero weights",
+			group: []Endpoint{
+				{RemoteHost: "a", Latency: 10},
+				{RemoteHost: "b", Latency: 10},
+			},
+			want: map[string]float64{"a": 0.5, "b": 0.5},
+		},
+		{
+			name: "twice slower is selected twice less often",
+			group: []Endpoint{
+				{RemoteHost: "a", Weight: 50, Latency: 9},
+				{RemoteHost: "b", Weight: 50, Latency: 15, Jitter: 4},
+			},
+			want: map[string]float64{"a": 2.0 / 3, "b": 1.0 / 3},
+		},
+		{
+			name: "zero weight is rarely selected",
+			group: []Endpoint{
+				{RemoteHost: "a", Weight: 10, Latency: 10},
+				{RemoteHost: "b", Weight: 0, Latency: 10},
+			},
+			want: map[string]float64{"a": 1, "b": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 5000
+			selected := map[string]int{}
+			for i := 0; i < runs; i++ {
+				selected[selectWeightedEndpoint(tt.group).RemoteHost]++
+			}
+
+			for host, want := range tt.want {
+				if got := float64(selected[host]) / float64(runs); math.Abs(got-want) > 0.05 {
+					t.Errorf("%q selected in %.1f%% of runs, want %.1f%%", host, got*100, want*100)
+				}
+			}
+		})
+	}
+}
+
+func TestIPv4Addresses(t *testing.T) {
+	tests := []struct {
+		name  string
+		addrs []string
+		want  []string
+	}{
+		{
+			name:  "ipv4 only",
+			addrs: []string{"192.0.2.1", "192.0.2.2"},
+			want:  []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			name:  "mixed",
+			addrs: []string{"2001:db8::1", "192.0.2.1", "192.0.2.2"},
+			want:  []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			name:  "ipv6 only",
+			addrs: []string{"2001:db8::1"},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ipv4Addresses(tt.addrs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ipv4Addresses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// serveNerf starts nerf-server answering pings and returns its port
+func TestMarkEndpointBad(t *testing.T) {
+	previous := Cfg.FailoverCooloff
+	defer func() {
+		Cfg.FailoverCooloff = previous
+		endpointsMutex.Lock()
+		badEndpoints = map[string]time.Time{}
+		endpointsMutex.Unlock()
+	}()
+
+	bad, good := Endpoint{RemoteHost: "bad"}, Endpoint{RemoteHost: "good"}
+
+	Cfg.FailoverCooloff = time.Minute
+	MarkEndpointBad(&bad)
+	if !endpointBad(&bad) || endpointBad(&good) {
+		t.Errorf("endpointBad() = %t, %t, want only the marked one bad", endpointBad(&bad), endpointBad(&good))
+	}
+
+	// The endpoint is selected again after the cool-off.
+	Cfg.FailoverCooloff = -time.Second
+	MarkEndpointBad(&bad)
+	if endpointBad(&bad) {
+		t.Error("endpointBad() = true after the cool-off")
+	}
+}
+
+func TestSelectEndpointFailover(t *testing.T) {
+	var specs []string
+	for _, address := range []string{"127.0.0.1", "127.0.0.2"} {
+		specs = append(specs, net.JoinHostPort(address, strconv.Itoa(int(serveNerfOn(t, address)))))
+	}
+
+	static, err := ParseStaticEndpoints(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousStatic, previousTimeout := Cfg.StaticEndpoints, Cfg.ProbeTimeout
+	Cfg.StaticEndpoints, Cfg.ProbeTimeout = static, time.Second
+	defer func() {
+		Cfg.StaticEndpoints, Cfg.ProbeTimeout = previousStatic, previousTimeout
+		endpointsMutex.Lock()
+		Cfg.Endpoints = nil
+		badEndpoints = map[string]time.Time{}
+		latencyEWMA = map[string]float64{}
+		endpointsMutex.Unlock()
+	}()
+
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.Login = "alice"
+	})
+	defer Cfg.State.UpdateSession(func(s *Session) {
+		s.Login = ""
+	})
+
+	MarkEndpointBad(&static[0])
+	for i := 0; i < 10; i++ {
+		e, err := SelectEndpoint(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.RemoteHost != static[1].RemoteHost {
+			t.Fatalf("SelectEndpoint() = %s, want %s while %s is cooling off", e.RemoteHost, static[1].RemoteHost, static[0].RemoteHost)
+		}
+	}
+
+	// Failed endpoints are still better than none.
+	MarkEndpointBad(&static[1])
+	if _, err := SelectEndpoint(context.Background()); err != nil {
+		t.Errorf("SelectEndpoint() error = %v while all endpoints are cooling off", err)
+	}
+}
+
+func TestUpdateLatencyEWMA(t *testing.T) {
+	defer func() {
+		latencyEWMA = map[string]float64{}
+	}()
+
+	probes := []struct {
+		latency int64
+		jitter  int64
+		want    float64
+		wantOk  bool
+	}{
+		{latency: 100, jitter: 0, want: 100, wantOk: true},
+		{latency: 40, jitter: 10, want: 0.3*50 + 0.7*100, wantOk: true},
+		{latency: math.MaxInt64, wantOk: false},
+		{latency: 20, jitter: 0, want: 20, wantOk: true},
+	}
+
+	e := Endpoint{RemoteHost: "a"}
+	for i, probe := range probes {
+		e.Latency, e.Jitter = probe.latency, probe.jitter
+		endpointsMutex.Lock()
+		updateLatencyEWMA(map[string]Endpoint{e.RemoteHost: e})
+		endpointsMutex.Unlock()
+
+		got, ok := smoothedLatency(&e)
+		if ok != probe.wantOk || math.Abs(got-probe.want) > 1e-9 {
+			t.Errorf("probe %d: smoothedLatency() = %f, %t, want %f, %t", i, got, ok, probe.want, probe.wantOk)
+		}
+	}
+}
+
+func TestBetterEndpoint(t *testing.T) {
+	previous := Cfg.MigrationMargin
+	Cfg.MigrationMargin = 0.3
+	defer func() {
+		Cfg.MigrationMargin = previous
+		endpointsMutex.Lock()
+		Cfg.Endpoints = nil
+		latencyEWMA = map[string]float64{}
+		badEndpoints = map[string]time.Time{}
+		endpointsMutex.Unlock()
+	}()
+
+	current := Endpoint{RemoteHost: "current", Priority: 10, Latency: 100}
+
+	tests := []struct {
+		name      string
+		candidate Endpoint
+		latency   float64
+		bad       bool
+		want      bool
+	}{
+		{name: "faster", candidate: Endpoint{RemoteHost: "candidate", Priority: 10, Latency: 50}, latency: 50, want: true},
+		{name: "faster of better priority", candidate: Endpoint{RemoteHost: "candidate", Priority: 5, Latency: 50}, latency: 50, want: true},
+		{name: "within margin", candidate: Endpoint{RemoteHost: "candidate", Priority: 10, Latency: 80}, latency: 80, want: false},
+		{name: "worse priority", candidate: Endpoint{RemoteHost: "candidate", Priority: 20, Latency: 10}, latency: 10, want: false},
+		{name: "cooling off", candidate: Endpoint{RemoteHost: "candidate", Priority: 10, Latency: 10}, latency: 10, bad: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpointsMutex.Lock()
+			Cfg.Endpoints = map[string]Endpoint{current.RemoteHost: current, tt.candidate.RemoteHost: tt.candidate}
+			latencyEWMA = map[string]float64{current.RemoteHost: 100, tt.candidate.RemoteHost: tt.latency}
+			badEndpoints = map[string]time.Time{}
+			if tt.bad {
+				badEndpoints[tt.candidate.RemoteHost] = time.Now().Add(time.Minute)
+			}
+			endpointsMutex.Unlock()
+
+			got, ok := betterEndpoint(&current)
+			if ok != tt.want {
+				t.Fatalf("betterEndpoint() = %s, %t, want %t", got.RemoteHost, ok, tt.want)
+			}
+			if ok && got.RemoteHost != tt.candidate.RemoteHost {
+				t.Errorf("betterEndpoint() = %s, want %s", got.RemoteHost, tt.candidate.RemoteHost)
+			}
+		})
+	}
+
+	// The current endpoint without probes is left to checkEndpoint.
+	endpointsMutex.Lock()
+	delete(latencyEWMA, current.RemoteHost)
+	endpointsMutex.Unlock()
+	if got, ok := betterEndpoint(&current); ok {
+		t.Errorf("betterEndpoint() = %s without probes of the current endpoint", got.RemoteHost)
+	}
+}
+
+func serveNerf(t *testing.T) uint16 {
+	return serveNerfOn(t, "127.0.0.1")
+}
+
+// serveNerfOn starts nerf-server answering pings on the address and returns its port
+func serveNerfOn(t *testing.T, address string) uint16 {
+	lis, err := net.Listen("tcp", net.JoinHostPort(address, "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	RegisterServerServer(server, &Server{})
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	return uint16(lis.Addr().(*net.TCPAddr).Port)
+}
+
+func TestProbeEndpoint(t *testing.T) {
+	port := serveNerf(t)
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.Login = "alice"
+	})
+	defer Cfg.State.UpdateSession(func(s *Session) {
+		s.Login = ""
+	})
+
+	tests := []struct {
+		name          string
+		remoteIPs     []string
+		wantRemoteIP  string
+		wantReachable bool
+	}{
+		{
+			name:          "reachable",
+			remoteIPs:     []string{"127.0.0.1"},
+			wantRemoteIP:  "127.0.0.1",
+			wantReachable: true,
+		},
+		{
+			// The unreachable address must not use up the timeout of the others.
+			name:          "unreachable address first",
+			remoteIPs:     []string{"192.0.2.1", "127.0.0.1"},
+			wantRemoteIP:  "127.0.0.1",
+			wantReachable: true,
+		},
+		{
+			name:          "unreachable",
+			remoteIPs:     []string{"192.0.2.1"},
+			wantRemoteIP:  "192.0.2.1",
+			wantReachable: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			e := Endpoint{RemoteHost: "localhost", RemoteIP: tt.remoteIPs[0], RemoteIPs: tt.remoteIPs, Port: port}
+			probeEndpoint(ctx, &e)
+
+			if e.RemoteIP != tt.wantRemoteIP {
+				t.Errorf("RemoteIP = %s, want %s", e.RemoteIP, tt.wantRemoteIP)
+			}
+			if reachable := e.Latency != math.MaxInt64; reachable != tt.wantReachable {
+				t.Errorf("reachable = %t, want %t", reachable, tt.wantReachable)
+			}
+		})
+	}
+}
+
+func TestDNSEndpoints(t *testing.T) {
+	zone := testZone{
+		"_vpn._udp.nerf.test.": {
+			srvRecord("dual.nerf.test.", 10, 10, 300),
+			srvRecord("v6.nerf.test.", 10, 10, 300),
+		},
+		"dual.nerf.test.": {txtRecord("Dual stack", 300), aaaaRecord("2001:db8::1", 300), aRecord("192.0.2.1", 300)},
+		"v6.nerf.test.":   {txtRecord("IPv6 only", 300), aaaaRecord("2001:db8::2", 300)},
+	}
+
+	resolver, zoneName := Cfg.Resolver, DNSAutoDiscoverZone
+	defer func() {
+		Cfg.Resolver, DNSAutoDiscoverZone = resolver, zoneName
+	}()
+	Cfg.Resolver, DNSAutoDiscoverZone = serveDoH(t, zone), "nerf.test"
+
+	// IPv6 addresses are never probed, an endpoint without IPv4 address fails the discovery.
+	if _, _, err := dnsEndpoints(context.Background()); err == nil {
+		t.Error("dnsEndpoints() with an IPv6 only endpoint succeeded")
+	}
+
+	zone["_vpn._udp.nerf.test."] = zone["_vpn._udp.nerf.test."][:1]
+	endpoints, _, err := dnsEndpoints(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := endpoints["dual.nerf.test."]; e.RemoteIP != "192.0.2.1" || !reflect.DeepEqual(e.RemoteIPs, []string{"192.0.2.1"}) {
+		t.Errorf("dnsEndpoints() = %+v, want only 192.0.2.1", e)
+	}
+}
+
+func TestGetVPNEndpoints(t *testing.T) {
+	var specs []string
+	for _, address := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"} {
+		specs = append(specs, net.JoinHostPort(address, strconv.Itoa(int(serveNerfOn(t, address)))))
+	}
+	specs = append(specs, "192.0.2.1:9000=unreachable")
+
+	static, err := ParseStaticEndpoints(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousStatic, previousTimeout := Cfg.StaticEndpoints, Cfg.ProbeTimeout
+	Cfg.StaticEndpoints, Cfg.ProbeTimeout = static, time.Second
+	defer func() {
+		Cfg.StaticEndpoints, Cfg.ProbeTimeout = previousStatic, previousTimeout
+		endpointsMutex.Lock()
+		Cfg.Endpoints = nil
+		latencyEWMA = map[string]float64{}
+		endpointsMutex.Unlock()
+	}()
+
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.Login = "alice"
+	})
+	defer Cfg.State.UpdateSession(func(s *Session) {
+		s.Login = ""
+	})
+
+	if err := getVPNEndpoints(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints := ProbedEndpoints()
+	if len(endpoints) != len(specs) {
+		t.Fatalf("ProbedEndpoints() = %v, want %d endpoints", endpoints, len(specs))
+	}
+	for _, e := range endpoints {
+		if reachable := e.Latency != math.MaxInt64; reachable != (e.RemoteHost != "192.0.2.1") {
+			t.Errorf("endpoint %s reachable = %t", e.RemoteHost, reachable)
+		}
+	}
+}
+
+func TestProbedEndpoints(t *testing.T) {
+	endpointsMutex.Lock()
+	Cfg.Endpoints = map[string]Endpoint{
+		"unreachable": {RemoteHost: "unreachable", Priority: 10, Latency: math.MaxInt64},
+		"slow":        {RemoteHost: "slow", Priority: 10, Latency: 30},
+		"jittery":     {RemoteHost: "jittery", Priority: 10, Latency: 10, Jitter: 30},
+		"fast":        {RemoteHost: "fast", Priority: 10, Latency: 20},
+		"backup":      {RemoteHost: "backup", Priority: 20, Latency: 1},
+		"b-tie":       {RemoteHost: "b-tie", Priority: 30, Latency: 5},
+		"a-tie":       {RemoteHost: "a-tie", Priority: 30, Latency: 5},
+	}
+	endpointsMutex.Unlock()
+	defer func() {
+		endpointsMutex.Lock()
+		Cfg.Endpoints = nil
+		endpointsMutex.Unlock()
+	}()
+
+	want := []string{"fast", "slow", "jittery", "unreachable", "backup", "a-tie", "b-tie"}
+
+	var got []string
+	for _, e := range ProbedEndpoints() {
+		got = append(got, e.RemoteHost)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ProbedEndpoints() = %v, want %v", got, want)
+	}
+}
+
+func TestEndpointPreferred(t *testing.T) {
+	e := Endpoint{
+		Description: "Vilnius, Lithuania",
+		RemoteHost:  "vpn-lt.example.com.",
+		RemoteIPs:   []string{"192.0.2.1", "192.0.2.2"},
+	}
+
+	tests := []struct {
+		name     string
+		endpoint string
+		region   string
+		want     bool
+	}{
+		{name: "no preference", want: true},
+		{name: "host", endpoint: "vpn-lt.example.com", want: true},
+		{name: "rooted host", endpoint: "vpn-lt.example.com.", want: true},
+		{name: "address", endpoint: "192.0.2.2", want: true},
+		{name: "other endpoint", endpoint: "vpn-us.example.com", want: false},
+		{name: "region", region: "lithuania", want: true},
+		{name: "other region", region: "Germany", want: false},
+		{name: "endpoint and region", endpoint: "192.0.2.1", region: "Vilnius", want: true},
+		{name: "endpoint of other region", endpoint: "192.0.2.1", region: "Germany", want: false},
+	}
+
+	defer func() {
+		Cfg.PreferredEndpoint = ""
+		Cfg.PreferredRegion = ""
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Cfg.PreferredEndpoint = tt.endpoint
+			Cfg.PreferredRegion = tt.region
+
+			if got := endpointPreferred(&e); got != tt.want {
+				t.Errorf("endpointPreferred() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendToPriorityGroup(t *testing.T) {
+	var group []Endpoint
+	for _, e := range []Endpoint{
+		{RemoteHost: "c", Priority: 20},
+		{RemoteHost: "a", Priority: 10},
+		{RemoteHost: "d", Priority: 30},
+		{RemoteHost: "b", Priority: 10},
+	} {
+		group = appendToPriorityGroup(group, e)
+	}
+
+	var got []string
+	for _, e := range group {
+		got = append(got, e.RemoteHost)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("appendToPriorityGroup() = %v, want %v", got, want)
+	}
+}
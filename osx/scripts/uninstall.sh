@@ -18,10 +18,18 @@ do
     fi
 done
 
+HELPER="com.ton31337.nerf.helper.launchd"
+if sudo launchctl list $HELPER &> /dev/null; then
+    sudo launchctl unload -w /Library/LaunchDaemons/$HELPER.plist
+fi
+
 sudo rm -f /Library/LaunchDaemons/$SERVICE.plist
+sudo rm -f /Library/LaunchDaemons/$HELPER.plist
 sudo rm -rf /Library/Services/Nerf
 sudo rm -rf /Applications/Nerf.app
-sudo rm -f /tmp/nerf.sock
+sudo rm -rf /var/run/nerf /var/run/nerf-helper
+sudo dscl . -delete /Users/nerf-api &> /dev/null
+sudo dseditgroup -o delete nerf &> /dev/null
 
 IFS=$'\n'
 for service in $(networksetup -listallnetworkservices);
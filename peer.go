@@ -0,0 +1,196 @@
+package nerf
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/user"
+	"path"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// PeerAuthInfo identifies the local process calling nerf-api over the UNIX socket
+type PeerAuthInfo struct {
+	credentials.CommonAuthInfo
+	UID  uint32
+	GIDs []uint32
+	PID  int32
+}
+
+// AuthType returns the type of PeerAuthInfo
+func (PeerAuthInfo) AuthType() string {
+	return "peercred"
+}
+
+// peerCredentials are gRPC transport credentials for UNIX sockets
+type peerCredentials struct {
+	authorize func(info PeerAuthInfo) error
+}
+
+// PeerCredentials returns server credentials authorizing members of Cfg.ApiGroup
+func PeerCredentials() credentials.TransportCredentials {
+	return peerCredentials{authorize: authorizePeer}
+}
+
+func (peerCredentials) ClientHandshake(
+	ctx context.Context,
+	authority string,
+	conn net.Conn,
+) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, fmt.Errorf("peer credentials are for servers only")
+}
+
+func (c peerCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, nil, fmt.Errorf("not a UNIX socket connection")
+	}
+
+	info, err := readPeerCredentials(unixConn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// As with local credentials of gRPC, UNIX sockets never leave the host.
+	info.SecurityLevel = credentials.PrivacyAndIntegrity
+
+	if err := c.authorize(info); err != nil {
+		Cfg.Logger.Warn("refused local caller",
+			zap.Uint32("UID", info.UID),
+			zap.Int32("PID", info.PID),
+			zap.Error(err))
+		return nil, nil, err
+	}
+
+	return conn, info, nil
+}
+
+func (peerCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "peercred"}
+}
+
+func (c peerCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (peerCredentials) OverrideServerName(string) error {
+	return nil
+}
+
+// authorizePeer allows root and members of Cfg.ApiGroup
+func authorizePeer(info PeerAuthInfo) error {
+	if info.UID == 0 || Cfg.ApiGroup == "" {
+		return nil
+	}
+
+	group, err := user.LookupGroup(Cfg.ApiGroup)
+	if err != nil {
+		return err
+	}
+
+	for _, gid := range info.GIDs {
+		if strconv.FormatUint(uint64(gid), 10) == group.Gid {
+			return nil
+		}
+	}
+
+	u, err := user.LookupId(strconv.FormatUint(uint64(info.UID), 10))
+	if err != nil {
+		return err
+	}
+	groups, err := u.GroupIds()
+	if err != nil {
+		return err
+	}
+	for _, gid := range groups {
+		if gid == group.Gid {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("user %s is not a member of group %s", u.Username, Cfg.ApiGroup)
+}
+
+// ApiSocket returns the path of nerf-api UNIX socket
+func ApiSocket() string {
+	return path.Join(ApiSocketDir(), "nerf.sock")
+}
+
+// callerUID returns the UID of the local process making the request
+func callerUID(ctx context.Context) (uint32, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+
+	info, ok := p.AuthInfo.(PeerAuthInfo)
+	if !ok {
+		return 0, false
+	}
+
+	return info.UID, true
+}
+
+// sessionOwner is the UID of the user who created the current session
+var sessionOwner struct {
+	sync.Mutex
+	uid   uint32
+	bound bool
+}
+
+// bindSession makes the caller the owner of the session
+func bindSession(ctx context.Context) {
+	uid, ok := callerUID(ctx)
+	if !ok {
+		return
+	}
+
+	sessionOwner.Lock()
+	defer sessionOwner.Unlock()
+
+	sessionOwner.uid = uid
+	sessionOwner.bound = true
+}
+
+// checkSessionOwner allows only the owner of the session and root to change it
+func checkSessionOwner(ctx context.Context) error {
+	if Cfg.State.State() == Status_DISCONNECTED || ownsSession(ctx) {
+		return nil
+	}
+
+	sessionOwner.Lock()
+	defer sessionOwner.Unlock()
+
+	return NewError(codes.PermissionDenied, ReasonNotSessionOwner, "The connection belongs to another user",
+		map[string]string{
+			"uid": strconv.FormatUint(uint64(sessionOwner.uid), 10),
+		})
+}
+
+// ownsSession checks if the caller is root or the owner of the last session
+func ownsSession(ctx context.Context) bool {
+	uid, ok := callerUID(ctx)
+	if !ok || uid == 0 {
+		return true
+	}
+
+	sessionOwner.Lock()
+	defer sessionOwner.Unlock()
+
+	return !sessionOwner.bound || sessionOwner.uid == uid
+}
+
+// visibleStatus returns the status as seen by the caller
+func visibleStatus(ctx context.Context, current *Status) *Status {
+	if ownsSession(ctx) {
+		return current
+	}
+
+	return &Status{State: current.State, Since: current.Since}
+}
@@ -0,0 +1,48 @@
+package nerf
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// ApiSocketDir is the runtime directory of nerf-api UNIX socket, writable by nerf-api only
+func ApiSocketDir() string {
+	return "/var/run/nerf"
+}
+
+// readPeerCredentials reads the identity of the peer with LOCAL_PEERCRED
+func readPeerCredentials(conn *net.UnixConn) (PeerAuthInfo, error) {
+	var xucred *unix.Xucred
+	var pid int
+	var credErr error
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return PeerAuthInfo{}, err
+	}
+
+	err = raw.Control(func(fd uintptr) {
+		xucred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+		if credErr == nil {
+			pid, credErr = unix.GetsockoptInt(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+		}
+	})
+	if err != nil {
+		return PeerAuthInfo{}, err
+	}
+	if credErr != nil {
+		return PeerAuthInfo{}, credErr
+	}
+
+	gids := make([]uint32, 0, xucred.Ngroups)
+	for i := 0; i < int(xucred.Ngroups); i++ {
+		gids = append(gids, xucred.Groups[i])
+	}
+
+	return PeerAuthInfo{
+		UID:  xucred.Uid,
+		GIDs: gids,
+		PID:  int32(pid),
+	}, nil
+}
@@ -0,0 +1,39 @@
+package nerf
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// ApiSocketDir is the runtime directory of nerf-api UNIX socket, writable by nerf-api only
+func ApiSocketDir() string {
+	return "/run/nerf"
+}
+
+// readPeerCredentials reads the identity of the peer with SO_PEERCRED
+func readPeerCredentials(conn *net.UnixConn) (PeerAuthInfo, error) {
+	var ucred *unix.Ucred
+	var credErr error
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return PeerAuthInfo{}, err
+	}
+
+	err = raw.Control(func(fd uintptr) {
+		ucred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return PeerAuthInfo{}, err
+	}
+	if credErr != nil {
+		return PeerAuthInfo{}, credErr
+	}
+
+	return PeerAuthInfo{
+		UID:  ucred.Uid,
+		GIDs: []uint32{ucred.Gid},
+		PID:  ucred.Pid,
+	}, nil
+}
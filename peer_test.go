@@ -0,0 +1,232 @@
+package nerf
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+	"testing"
+
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// callerContext returns the context of a request made by the local user
+func callerContext(uid uint32) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: PeerAuthInfo{UID: uid}})
+}
+
+func TestCheckSessionOwner(t *testing.T) {
+	state := Cfg.State
+	defer func() {
+		Cfg.State = state
+		sessionOwner.Lock()
+		sessionOwner.uid, sessionOwner.bound = 0, false
+		sessionOwner.Unlock()
+	}()
+
+	Cfg.State = NewStateMachine()
+	if err := Cfg.State.Transition(Status_DISCOVERING, func(*Status) {
+		bindSession(callerContext(1000))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reconnects of nerf-api itself keep the owner.
+	bindSession(context.Background())
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr bool
+	}{
+		{name: "owner", ctx: callerContext(1000)},
+		{name: "root", ctx: callerContext(0)},
+		{name: "other user", ctx: callerContext(1001), wantErr: true},
+		{name: "unknown caller", ctx: context.Background()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSessionOwner(tt.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkSessionOwner() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr && ErrorReason(err) != ReasonNotSessionOwner {
+				t.Errorf("ErrorReason() = %q, want %s", ErrorReason(err), ReasonNotSessionOwner)
+			}
+		})
+	}
+
+	// Anybody may start a new session once the previous one is over.
+	Cfg.State.Fail(fmt.Errorf("failed"))
+	if err := checkSessionOwner(callerContext(1001)); err != nil {
+		t.Errorf("checkSessionOwner() after disconnect error = %v", err)
+	}
+}
+
+func TestVisibleStatus(t *testing.T) {
+	defer func() {
+		sessionOwner.Lock()
+		sessionOwner.uid, sessionOwner.bound = 0, false
+		sessionOwner.Unlock()
+	}()
+	bindSession(callerContext(1000))
+
+	current := &Status{
+		State:       Status_DISCONNECTED,
+		Since:       1,
+		RemoteHost:  "vpn-lt.example.com",
+		ClientIP:    "10.0.0.2",
+		Teams:       []string{"sre"},
+		Error:       "Can't start Nebula",
+		ErrorReason: ReasonNebulaFailed,
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want *Status
+	}{
+		{name: "owner", ctx: callerContext(1000), want: current},
+		{name: "root", ctx: callerContext(0), want: current},
+		// The last session stays private after it's over.
+		{name: "other user", ctx: callerContext(1001), want: &Status{State: Status_DISCONNECTED, Since: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := visibleStatus(tt.ctx, current); !proto.Equal(got, tt.want) {
+				t.Errorf("visibleStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizePeer(t *testing.T) {
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("no nobody user:", err)
+	}
+	uid, _ := strconv.ParseUint(nobody.Uid, 10, 32)
+	primary, err := user.LookupGroupId(nobody.Gid)
+	if err != nil {
+		t.Skip("no primary group of nobody:", err)
+	}
+	root, err := user.LookupGroupId("0")
+	if err != nil {
+		t.Skip("no group 0:", err)
+	}
+	rootGID, _ := strconv.ParseUint(root.Gid, 10, 32)
+
+	group := Cfg.ApiGroup
+	defer func() {
+		Cfg.ApiGroup = group
+	}()
+
+	tests := []struct {
+		name    string
+		group   string
+		info    PeerAuthInfo
+		wantErr bool
+	}{
+		{name: "root", group: root.Name, info: PeerAuthInfo{UID: 0}},
+		{name: "no group configured", group: "", info: PeerAuthInfo{UID: uint32(uid)}},
+		{name: "group of the process", group: root.Name, info: PeerAuthInfo{UID: uint32(uid), GIDs: []uint32{uint32(rootGID)}}},
+		{name: "group of the user", group: primary.Name, info: PeerAuthInfo{UID: uint32(uid)}},
+		{name: "not a member", group: root.Name, info: PeerAuthInfo{UID: uint32(uid)}, wantErr: true},
+		{name: "unknown group", group: "nerf-test-missing", info: PeerAuthInfo{UID: uint32(uid)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Cfg.ApiGroup = tt.group
+
+			if err := authorizePeer(tt.info); (err != nil) != tt.wantErr {
+				t.Errorf("authorizePeer() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPeerCredentialsHandshake(t *testing.T) {
+	lis, err := net.Listen("unix", path.Join(t.TempDir(), "test.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	tests := []struct {
+		name    string
+		refuse  bool
+		wantErr bool
+	}{
+		{name: "authorized"},
+		{name: "refused", refuse: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := net.Dial("unix", lis.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			conn, err := lis.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			var authorized PeerAuthInfo
+			creds := peerCredentials{authorize: func(info PeerAuthInfo) error {
+				authorized = info
+				if tt.refuse {
+					return fmt.Errorf("refused")
+				}
+				return nil
+			}}
+
+			_, authInfo, err := creds.ServerHandshake(conn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ServerHandshake() error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			// The identity of the peer comes from the kernel.
+			if authorized.UID != uint32(os.Getuid()) {
+				t.Errorf("UID = %d, want %d", authorized.UID, os.Getuid())
+			}
+			if authorized.PID != 0 && authorized.PID != int32(os.Getpid()) {
+				t.Errorf("PID = %d, want %d", authorized.PID, os.Getpid())
+			}
+			if !tt.wantErr {
+				if info, ok := authInfo.(PeerAuthInfo); !ok || info.UID != authorized.UID {
+					t.Errorf("AuthInfo = %#v", authInfo)
+				}
+			}
+		})
+	}
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+	client, err := net.Dial("tcp", tcp.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := tcp.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, _, err := PeerCredentials().ServerHandshake(conn); err == nil {
+		t.Error("ServerHandshake() of a TCP connection succeeded")
+	}
+}
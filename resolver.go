@@ -0,0 +1,337 @@
+package nerf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Resolver looks up DNS records used for endpoint discovery
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// fallbackResolver queries resolvers in order until one of them succeeds
+type fallbackResolver struct {
+	names     []string
+	resolvers []Resolver
+}
+
+// dohResolver resolves names using DNS-over-HTTPS (RFC 8484)
+type dohResolver struct {
+	url    string
+	client *http.Client
+}
+
+// ttlRecorder collects the lowest TTL of DNS records found during lookups
+type ttlRecorder struct {
+	mutex sync.Mutex
+	ttl   time.Duration
+	known bool
+}
+
+type ttlRecorderContextKey struct{}
+
+// withTTLRecorder returns a context recording TTLs of DNS records looked up with it
+func withTTLRecorder(ctx context.Context) (context.Context, *ttlRecorder) {
+	recorder := &ttlRecorder{}
+	return context.WithValue(ctx, ttlRecorderContextKey{}, recorder), recorder
+}
+
+// recordTTL records the TTL if the context has a recorder
+func recordTTL(ctx context.Context, ttl time.Duration) {
+	recorder, ok := ctx.Value(ttlRecorderContextKey{}).(*ttlRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if !recorder.known || ttl < recorder.ttl {
+		recorder.ttl = ttl
+		recorder.known = true
+	}
+}
+
+// TTL returns the lowest recorded TTL, or false if no resolver reported it
+func (r *ttlRecorder) TTL() (time.Duration, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.ttl, r.known
+}
+
+// NewResolver creates a resolver querying `system`, `host[:port]` or `https://...` specs in order
+func NewResolver(specs []string) (Resolver, error) {
+	r := &fallbackResolver{}
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		switch {
+		case spec == "":
+			continue
+		case spec == "system":
+			r.resolvers = append(r.resolvers, net.DefaultResolver)
+		case strings.HasPrefix(spec, "https://"):
+			r.resolvers = append(r.resolvers, &dohResolver{
+				url:    spec,
+				client: &http.Client{Timeout: 5 * time.Second},
+			})
+		default:
+			address := spec
+			if _, _, err := net.SplitHostPort(spec); err != nil {
+				address = net.JoinHostPort(spec, "53")
+			}
+			if host, _, err := net.SplitHostPort(address); err != nil || net.ParseIP(host) == nil {
+				return nil, fmt.Errorf("invalid DNS resolver address: %s", spec)
+			}
+			r.resolvers = append(r.resolvers, &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					d := net.Dialer{
+						Timeout: 3 * time.Second,
+					}
+					return d.DialContext(ctx, network, address)
+				},
+			})
+		}
+		r.names = append(r.names, spec)
+	}
+
+	if len(r.resolvers) == 0 {
+		return nil, fmt.Errorf("no DNS resolvers configured")
+	}
+
+	return r, nil
+}
+
+func (r *fallbackResolver) lookup(lookup func(Resolver) error) error {
+	var err error
+
+	for i, resolver := range r.resolvers {
+		if err = lookup(resolver); err == nil {
+			return nil
+		}
+		Cfg.Logger.Debug("DNS lookup failed, trying next resolver",
+			zap.String("Resolver", r.names[i]),
+			zap.Error(err))
+	}
+
+	return err
+}
+
+func (r *fallbackResolver) LookupSRV(
+	ctx context.Context,
+	service, proto, name string,
+) (cname string, records []*net.SRV, err error) {
+	err = r.lookup(func(resolver Resolver) error {
+		cname, records, err = resolver.LookupSRV(ctx, service, proto, name)
+		return err
+	})
+	return cname, records, err
+}
+
+func (r *fallbackResolver) LookupTXT(ctx context.Context, name string) (records []string, err error) {
+	err = r.lookup(func(resolver Resolver) error {
+		records, err = resolver.LookupTXT(ctx, name)
+		return err
+	})
+	return records, err
+}
+
+func (r *fallbackResolver) LookupHost(ctx context.Context, host string) (addrs []string, err error) {
+	err = r.lookup(func(resolver Resolver) error {
+		addrs, err = resolver.LookupHost(ctx, host)
+		return err
+	})
+	return addrs, err
+}
+
+func (r *dohResolver) exchange(
+	ctx context.Context,
+	name string,
+	qtype dnsmessage.Type,
+) ([]dnsmessage.Resource, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: qname, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/dns-message")
+	request.Header.Set("Accept", "application/dns-message")
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed DNS-over-HTTPS query: %s", response.Status)
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var answer dnsmessage.Message
+	if err := answer.Unpack(body); err != nil {
+		return nil, err
+	}
+
+	switch answer.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, &net.DNSError{Err: "no such host", Name: name, Server: r.url, IsNotFound: true}
+	default:
+		return nil, &net.DNSError{Err: answer.RCode.String(), Name: name, Server: r.url}
+	}
+
+	var resources []dnsmessage.Resource
+	for _, resource := range answer.Answers {
+		if resource.Header.Type == qtype {
+			resources = append(resources, resource)
+			recordTTL(ctx, time.Duration(resource.Header.TTL)*time.Second)
+		}
+	}
+
+	if len(resources) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: name, Server: r.url, IsNotFound: true}
+	}
+
+	return resources, nil
+}
+
+func (r *dohResolver) LookupSRV(
+	ctx context.Context,
+	service, proto, name string,
+) (string, []*net.SRV, error) {
+	target := "_" + service + "._" + proto + "." + name
+
+	resources, err := r.exchange(ctx, target, dnsmessage.TypeSRV)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var records []*net.SRV
+	for _, resource := range resources {
+		srv := resource.Body.(*dnsmessage.SRVResource)
+		records = append(records, &net.SRV{
+			Target:   srv.Target.String(),
+			Port:     srv.Port,
+			Priority: srv.Priority,
+			Weight:   srv.Weight,
+		})
+	}
+	sortSRV(records)
+
+	return target, records, nil
+}
+
+// sortSRV orders SRV records by priority and weight (RFC 2782)
+func sortSRV(records []*net.SRV) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Priority < records[j].Priority
+	})
+
+	for i := 0; i < len(records); {
+		j := i + 1
+		for j < len(records) && records[j].Priority == records[i].Priority {
+			j++
+		}
+		shuffleSRVByWeight(records[i:j])
+		i = j
+	}
+}
+
+// shuffleSRVByWeight orders records randomly proportionally to their weight
+func shuffleSRVByWeight(records []*net.SRV) {
+	sum := 0
+	for _, record := range records {
+		sum += int(record.Weight)
+	}
+
+	for sum > 0 && len(records) > 1 {
+		pick := rand.Intn(sum)
+		for i, record := range records {
+			if pick < int(record.Weight) {
+				records[0], records[i] = records[i], records[0]
+				break
+			}
+			pick -= int(record.Weight)
+		}
+		sum -= int(records[0].Weight)
+		records = records[1:]
+	}
+}
+
+func (r *dohResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	resources, err := r.exchange(ctx, name, dnsmessage.TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []string
+	for _, resource := range resources {
+		records = append(records, strings.Join(resource.Body.(*dnsmessage.TXTResource).TXT, ""))
+	}
+
+	return records, nil
+}
+
+func (r *dohResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	var addrs []string
+
+	resources, errA := r.exchange(ctx, host, dnsmessage.TypeA)
+	for _, resource := range resources {
+		addrs = append(addrs, net.IP(resource.Body.(*dnsmessage.AResource).A[:]).String())
+	}
+
+	resources, errAAAA := r.exchange(ctx, host, dnsmessage.TypeAAAA)
+	for _, resource := range resources {
+		addrs = append(addrs, net.IP(resource.Body.(*dnsmessage.AAAAResource).AAAA[:]).String())
+	}
+
+	if len(addrs) == 0 {
+		if errA != nil {
+			return nil, errA
+		}
+		return nil, errAAAA
+	}
+
+	return addrs, nil
+}
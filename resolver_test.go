@@ -0,0 +1,329 @@
+package nerf
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testZone answers DNS questions with the records of the name
+type testZone map[string][]dnsmessage.Resource
+
+func srvRecord(target string, priority, weight uint16, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeSRV, TTL: ttl},
+		Body: &dnsmessage.SRVResource{
+			Priority: priority,
+			Weight:   weight,
+			Port:     DefaultServerPort,
+			Target:   dnsmessage.MustNewName(target),
+		},
+	}
+}
+
+func txtRecord(txt string, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeTXT, TTL: ttl},
+		Body:   &dnsmessage.TXTResource{TXT: []string{txt}},
+	}
+}
+
+func aRecord(ip string, ttl uint32) dnsmessage.Resource {
+	var a [4]byte
+	copy(a[:], net.ParseIP(ip).To4())
+
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: a},
+	}
+}
+
+func aaaaRecord(ip string, ttl uint32) dnsmessage.Resource {
+	var aaaa [16]byte
+	copy(aaaa[:], net.ParseIP(ip).To16())
+
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeAAAA, TTL: ttl},
+		Body:   &dnsmessage.AAAAResource{AAAA: aaaa},
+	}
+}
+
+func (z testZone) answer(query []byte) ([]byte, error) {
+	var request dnsmessage.Message
+	if err := request.Unpack(query); err != nil {
+		return nil, err
+	}
+
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 request.ID,
+			Response:           true,
+			RecursionDesired:   request.RecursionDesired,
+			RecursionAvailable: true,
+		},
+		Questions: request.Questions,
+	}
+
+	question := request.Questions[0]
+	resources, ok := z[strings.ToLower(question.Name.String())]
+	if !ok {
+		response.RCode = dnsmessage.RCodeNameError
+	}
+	for _, resource := range resources {
+		if resource.Header.Type != question.Type {
+			continue
+		}
+		resource.Header.Name = question.Name
+		resource.Header.Class = dnsmessage.ClassINET
+		response.Answers = append(response.Answers, resource)
+	}
+
+	return response.Pack()
+}
+
+// serveDNS serves the zone over UDP and returns the address of the server
+func serveDNS(t *testing.T, zone testZone) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	go func() {
+		buffer := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			response, err := zone.answer(buffer[:n])
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(response, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// serveDoH serves the zone over DNS-over-HTTPS and returns a resolver using it
+func serveDoH(t *testing.T, zone testZone) *dohResolver {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, err := ioutil.ReadAll(r.Body)
+		if err != nil || r.Header.Get("Content-Type") != "application/dns-message" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response, err := zone.answer(query)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return &dohResolver{url: server.URL, client: server.Client()}
+}
+
+func testEndpointsZone() testZone {
+	return testZone{
+		"_vpn._udp.nerf.test.": {
+			srvRecord("c.nerf.test.", 20, 0, 300),
+			srvRecord("a.nerf.test.", 10, 90, 600),
+			srvRecord("d.nerf.test.", 20, 100, 300),
+			srvRecord("b.nerf.test.", 10, 10, 120),
+		},
+		"a.nerf.test.": {txtRecord("Endpoint A", 300), aRecord("192.0.2.1", 300)},
+		"b.nerf.test.": {txtRecord("Endpoint B", 300), aRecord("192.0.2.2", 60)},
+	}
+}
+
+func TestResolverSRVOrder(t *testing.T) {
+	resolvers := map[string]Resolver{
+		"dns": func() Resolver {
+			r, err := NewResolver([]string{serveDNS(t, testEndpointsZone())})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return r
+		}(),
+		"doh": serveDoH(t, testEndpointsZone()),
+	}
+
+	for name, r := range resolvers {
+		t.Run(name, func(t *testing.T) {
+			first := map[string]int{}
+			runs := 200
+
+			for i := 0; i < runs; i++ {
+				_, records, err := r.LookupSRV(context.Background(), "vpn", "udp", "nerf.test")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(records) != 4 {
+					t.Fatalf("got %d records, want 4", len(records))
+				}
+				if !sort.SliceIsSorted(records, func(i, j int) bool {
+					return records[i].Priority < records[j].Priority
+				}) {
+					t.Fatalf("records are not ordered by priority: %v", records)
+				}
+				// Zero weight is never picked before a non-zero one.
+				if records[2].Target != "d.nerf.test." || records[3].Target != "c.nerf.test." {
+					t.Fatalf("zero weight record ordered first: %s, %s", records[2].Target, records[3].Target)
+				}
+				first[records[0].Target]++
+			}
+
+			// Weights 90 and 10 pick the first one 90% of the time.
+			if share := float64(first["a.nerf.test."]) / float64(runs); share < 0.75 || share > 0.99 {
+				t.Errorf("a.nerf.test. is first in %.0f%% of lookups, want ~90%%", share*100)
+			}
+		})
+	}
+}
+
+func TestSortSRV(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []*net.SRV
+		want    []string
+	}{
+		{
+			name:    "empty",
+			records: nil,
+			want:    nil,
+		},
+		{
+			name: "priorities",
+			records: []*net.SRV{
+				{Target: "c", Priority: 30},
+				{Target: "a", Priority: 10},
+				{Target: "b", Priority: 20},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "zero weight last",
+			records: []*net.SRV{
+				{Target: "zero", Priority: 10},
+				{Target: "weighted", Priority: 10, Weight: 1},
+			},
+			want: []string{"weighted", "zero"},
+		},
+		{
+			name: "all zero weights keep the order",
+			records: []*net.SRV{
+				{Target: "a", Priority: 10},
+				{Target: "b", Priority: 10},
+			},
+			want: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortSRV(tt.records)
+
+			var got []string
+			for _, record := range tt.records {
+				got = append(got, record.Target)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoHResolver(t *testing.T) {
+	r := serveDoH(t, testEndpointsZone())
+
+	ctx, recorder := withTTLRecorder(context.Background())
+
+	txt, err := r.LookupTXT(ctx, "a.nerf.test")
+	if err != nil || len(txt) != 1 || txt[0] != "Endpoint A" {
+		t.Errorf("LookupTXT() = %v, %v", txt, err)
+	}
+
+	addrs, err := r.LookupHost(ctx, "b.nerf.test")
+	if err != nil || len(addrs) != 1 || addrs[0] != "192.0.2.2" {
+		t.Errorf("LookupHost() = %v, %v", addrs, err)
+	}
+
+	if ttl, ok := recorder.TTL(); !ok || ttl != time.Minute {
+		t.Errorf("TTL() = %s, %t, want the lowest TTL 1m0s", ttl, ok)
+	}
+
+	_, err = r.LookupHost(context.Background(), "missing.nerf.test")
+	if dnsErr, ok := err.(*net.DNSError); !ok || !dnsErr.IsNotFound {
+		t.Errorf("LookupHost() of missing name = %v, want not found", err)
+	}
+}
+
+func TestFallbackResolver(t *testing.T) {
+	failing := &dohResolver{url: "https://127.0.0.1:1/dns-query", client: &http.Client{Timeout: time.Second}}
+	working := serveDoH(t, testEndpointsZone())
+
+	tests := []struct {
+		name      string
+		resolvers []Resolver
+		wantErr   bool
+	}{
+		{name: "first works", resolvers: []Resolver{working, failing}},
+		{name: "falls back", resolvers: []Resolver{failing, working}},
+		{name: "all fail", resolvers: []Resolver{failing, failing}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &fallbackResolver{resolvers: tt.resolvers}
+			for range tt.resolvers {
+				r.names = append(r.names, "test")
+			}
+
+			addrs, err := r.LookupHost(context.Background(), "a.nerf.test")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LookupHost() = %v, want error", addrs)
+				}
+				return
+			}
+			if err != nil || len(addrs) != 1 || addrs[0] != "192.0.2.1" {
+				t.Errorf("LookupHost() = %v, %v", addrs, err)
+			}
+		})
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	tests := []struct {
+		specs   []string
+		wantErr bool
+	}{
+		{specs: []string{"system"}},
+		{specs: []string{"1.1.1.1", "8.8.8.8:53", "https://dns.google/dns-query"}},
+		{specs: []string{"", " "}, wantErr: true},
+		{specs: []string{"dns.google"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, err := NewResolver(tt.specs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewResolver(%q) error = %v, wantErr %t", tt.specs, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package nerf
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// StateMachine tracks the connection state of nerf-api
+type StateMachine struct {
+	mutex       sync.Mutex
+	status      *Status
+	session     Session
+	subscribers map[chan *Status]struct{}
+}
+
+// Session is the connection established by nerf-api, guarded by the state lock
+type Session struct {
+	Login    string
+	Token    string
+	Endpoint Endpoint
+	ClientIP string
+	Nebula   *NebulaProcess
+	// stopWatching stops the watchers of the endpoint
+	stopWatching context.CancelFunc
+}
+
+// stateTransitions lists the states reachable from every state
+var stateTransitions = map[Status_State][]Status_State{
+	Status_DISCONNECTED:    {Status_DISCOVERING},
+	Status_DISCOVERING:     {Status_AUTHENTICATING, Status_DISCONNECTING, Status_DISCONNECTED},
+	Status_AUTHENTICATING:  {Status_CONFIGURING, Status_DISCONNECTING, Status_DISCONNECTED},
+	Status_CONFIGURING:     {Status_STARTING_NEBULA, Status_DISCONNECTING, Status_DISCONNECTED},
+	Status_STARTING_NEBULA: {Status_CONNECTED, Status_DISCONNECTING, Status_DISCONNECTED},
+	Status_CONNECTED:       {Status_RECONNECTING, Status_DISCONNECTING, Status_DISCONNECTED},
+	Status_RECONNECTING:    {Status_DISCOVERING, Status_DISCONNECTING, Status_DISCONNECTED},
+	Status_DISCONNECTING:   {Status_DISCONNECTED},
+}
+
+// NewStateMachine initializes StateMachine in Disconnected state
+func NewStateMachine() *StateMachine {
+	return &StateMachine{
+		status:      &Status{State: Status_DISCONNECTED, Since: time.Now().Unix()},
+		subscribers: make(map[chan *Status]struct{}),
+	}
+}
+
+// Status returns a copy of the current status
+func (m *StateMachine) Status() *Status {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return proto.Clone(m.status).(*Status)
+}
+
+// State returns the current state
+func (m *StateMachine) State() Status_State {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.status.State
+}
+
+// Session returns a copy of the current session
+func (m *StateMachine) Session() Session {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.session
+}
+
+// UpdateSession applies update to the session without changing the state
+func (m *StateMachine) UpdateSession(update func(*Session)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	update(&m.session)
+}
+
+// Transition switches to the state if it's reachable, and applies update to the status
+func (m *StateMachine) Transition(to Status_State, update func(*Status)) error {
+	return m.TransitionSession(to, func(s *Status, _ *Session) {
+		if update != nil {
+			update(s)
+		}
+	})
+}
+
+// TransitionSession is Transition applying update to the session as well
+func (m *StateMachine) TransitionSession(to Status_State, update func(*Status, *Session)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	from := m.status.State
+	allowed := false
+	for _, state := range stateTransitions[from] {
+		allowed = allowed || state == to
+	}
+	if !allowed {
+		return fmt.Errorf("invalid state transition from %s to %s", from, to)
+	}
+
+	switch to {
+	case Status_DISCOVERING:
+		m.status = &Status{}
+	case Status_DISCONNECTED:
+		m.status = &Status{Error: m.status.Error, ErrorReason: m.status.ErrorReason}
+	}
+
+	m.status.State = to
+	m.status.Since = time.Now().Unix()
+	if update != nil {
+		update(m.status, &m.session)
+	}
+
+	Cfg.Logger.Debug("state changed",
+		zap.String("From", from.String()),
+		zap.String("To", to.String()))
+
+	m.notify()
+
+	return nil
+}
+
+// Update applies update to the status without changing the state
+func (m *StateMachine) Update(update func(*Status)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	update(m.status)
+	m.notify()
+}
+
+// Fail switches to Disconnected state reporting the error
+func (m *StateMachine) Fail(err error) {
+	_ = m.Transition(Status_DISCONNECTED, func(s *Status) {
+		s.Error = status.Convert(err).Message()
+		s.ErrorReason = ErrorReason(err)
+	})
+}
+
+// Subscribe returns a channel receiving the current status and all the changes
+func (m *StateMachine) Subscribe() chan *Status {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ch := make(chan *Status, 16)
+	ch <- proto.Clone(m.status).(*Status)
+	m.subscribers[ch] = struct{}{}
+
+	return ch
+}
+
+// Unsubscribe stops delivering status changes to the channel
+func (m *StateMachine) Unsubscribe(ch chan *Status) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.subscribers, ch)
+}
+
+// notify sends the status to subscribers, must be called with mutex locked
+func (m *StateMachine) notify() {
+	for ch := range m.subscribers {
+		s := proto.Clone(m.status).(*Status)
+		select {
+		case ch <- s:
+			continue
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+}
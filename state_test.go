@@ -0,0 +1,195 @@
+package nerf
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+// stateMachineIn returns a state machine brought to the state via allowed transitions
+func stateMachineIn(t *testing.T, state Status_State) *StateMachine {
+	paths := map[Status_State][]Status_State{
+		Status_DISCONNECTED:    nil,
+		Status_DISCOVERING:     {Status_DISCOVERING},
+		Status_AUTHENTICATING:  {Status_DISCOVERING, Status_AUTHENTICATING},
+		Status_CONFIGURING:     {Status_DISCOVERING, Status_AUTHENTICATING, Status_CONFIGURING},
+		Status_STARTING_NEBULA: {Status_DISCOVERING, Status_AUTHENTICATING, Status_CONFIGURING, Status_STARTING_NEBULA},
+		Status_CONNECTED: {Status_DISCOVERING, Status_AUTHENTICATING, Status_CONFIGURING, Status_STARTING_NEBULA,
+			Status_CONNECTED},
+		Status_RECONNECTING: {Status_DISCOVERING, Status_AUTHENTICATING, Status_CONFIGURING, Status_STARTING_NEBULA,
+			Status_CONNECTED, Status_RECONNECTING},
+		Status_DISCONNECTING: {Status_DISCOVERING, Status_DISCONNECTING},
+	}
+
+	m := NewStateMachine()
+	for _, to := range paths[state] {
+		if err := m.Transition(to, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return m
+}
+
+func TestStateMachineTransition(t *testing.T) {
+	tests := []struct {
+		from    Status_State
+		to      Status_State
+		allowed bool
+	}{
+		{from: Status_DISCONNECTED, to: Status_DISCOVERING, allowed: true},
+		{from: Status_DISCONNECTED, to: Status_CONNECTED, allowed: false},
+		{from: Status_DISCONNECTED, to: Status_DISCONNECTING, allowed: false},
+		{from: Status_DISCONNECTED, to: Status_DISCONNECTED, allowed: false},
+		{from: Status_DISCOVERING, to: Status_AUTHENTICATING, allowed: true},
+		{from: Status_DISCOVERING, to: Status_DISCOVERING, allowed: false},
+		{from: Status_DISCOVERING, to: Status_CONNECTED, allowed: false},
+		{from: Status_AUTHENTICATING, to: Status_CONFIGURING, allowed: true},
+		{from: Status_CONFIGURING, to: Status_STARTING_NEBULA, allowed: true},
+		{from: Status_STARTING_NEBULA, to: Status_CONNECTED, allowed: true},
+		{from: Status_CONNECTED, to: Status_DISCOVERING, allowed: false},
+		{from: Status_CONNECTED, to: Status_RECONNECTING, allowed: true},
+		{from: Status_CONNECTED, to: Status_DISCONNECTING, allowed: true},
+		{from: Status_CONNECTED, to: Status_DISCONNECTED, allowed: true},
+		{from: Status_RECONNECTING, to: Status_DISCOVERING, allowed: true},
+		{from: Status_DISCONNECTING, to: Status_DISCONNECTED, allowed: true},
+		{from: Status_DISCONNECTING, to: Status_RECONNECTING, allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from.String()+" to "+tt.to.String(), func(t *testing.T) {
+			m := stateMachineIn(t, tt.from)
+
+			updated := false
+			err := m.TransitionSession(tt.to, func(*Status, *Session) {
+				updated = true
+			})
+			if (err == nil) != tt.allowed {
+				t.Fatalf("Transition() error = %v, allowed %t", err, tt.allowed)
+			}
+			if updated != tt.allowed {
+				t.Errorf("update applied = %t, want %t", updated, tt.allowed)
+			}
+
+			want := tt.from
+			if tt.allowed {
+				want = tt.to
+			}
+			if got := m.State(); got != want {
+				t.Errorf("State() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestStateMachineResetsStatus(t *testing.T) {
+	m := stateMachineIn(t, Status_AUTHENTICATING)
+	m.Update(func(s *Status) {
+		s.RemoteHost = "vpn.example.com"
+	})
+	m.Fail(NewError(codes.Unavailable, ReasonNoEndpoints, "No reachable endpoints found", nil))
+
+	// The error of the failed attempt is kept until connecting again.
+	got := m.Status()
+	if got.State != Status_DISCONNECTED || got.RemoteHost != "" {
+		t.Errorf("Status() = %v, want disconnected without connection details", got)
+	}
+	if got.Error != "No reachable endpoints found" || got.ErrorReason != ReasonNoEndpoints {
+		t.Errorf("Status() = %v, want the error reported", got)
+	}
+
+	if err := m.Transition(Status_DISCOVERING, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Status(); got.Error != "" || got.ErrorReason != "" {
+		t.Errorf("Status() = %v, want the error cleared", got)
+	}
+}
+
+func TestStateMachineSession(t *testing.T) {
+	m := NewStateMachine()
+
+	if err := m.TransitionSession(Status_DISCOVERING, func(_ *Status, s *Session) {
+		s.Login = "alice"
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rejected transition leaves the session of the connect in progress intact.
+	if err := m.TransitionSession(Status_DISCOVERING, func(_ *Status, s *Session) {
+		s.Login = "eve"
+	}); err == nil {
+		t.Fatal("Transition() to the current state succeeded")
+	}
+	if got := m.Session().Login; got != "alice" {
+		t.Errorf("Session().Login = %q, want alice", got)
+	}
+
+	session := m.Session()
+	session.Login = "bob"
+	if got := m.Session().Login; got != "alice" {
+		t.Errorf("Session() returned the session itself, Login = %q", got)
+	}
+}
+
+func TestStateMachineSubscribe(t *testing.T) {
+	m := NewStateMachine()
+	ch := m.Subscribe()
+	defer m.Unsubscribe(ch)
+
+	if s := <-ch; s.State != Status_DISCONNECTED {
+		t.Errorf("first status = %s, want the current one", s.State)
+	}
+
+	// A slow subscriber still receives the latest status.
+	m.Transition(Status_DISCOVERING, nil)
+	for i := 0; i < cap(ch)+5; i++ {
+		m.Update(func(s *Status) {
+			s.Description = "latest"
+		})
+	}
+	m.Transition(Status_DISCONNECTING, nil)
+
+	var last *Status
+	for len(ch) > 0 {
+		last = <-ch
+	}
+	if last == nil || last.State != Status_DISCONNECTING {
+		t.Errorf("last status = %v, want %s", last, Status_DISCONNECTING)
+	}
+}
+
+func TestConnectAlreadyConnecting(t *testing.T) {
+	state := Cfg.State
+	defer func() {
+		Cfg.State = state
+		Cfg.PreferredEndpoint = ""
+	}()
+
+	Cfg.State = stateMachineIn(t, Status_CONFIGURING)
+	Cfg.State.UpdateSession(func(s *Session) {
+		s.Login = "alice"
+		s.Token = "token"
+	})
+	Cfg.PreferredEndpoint = "vpn-lt.example.com"
+
+	_, err := (&Api{}).Connect(context.Background(), &Request{
+		Login:    "eve",
+		Token:    "other",
+		Endpoint: "vpn-us.example.com",
+	})
+	if reason := ErrorReason(err); reason != ReasonAlreadyConnected {
+		t.Fatalf("Connect() error = %v, want %s", err, ReasonAlreadyConnected)
+	}
+
+	if session := Cfg.State.Session(); session.Login != "alice" || session.Token != "token" {
+		t.Errorf("session = %+v, want the credentials kept", session)
+	}
+	if Cfg.PreferredEndpoint != "vpn-lt.example.com" {
+		t.Errorf("PreferredEndpoint = %q, want the one of the connect in progress", Cfg.PreferredEndpoint)
+	}
+	if got := Cfg.State.State(); got != Status_CONFIGURING {
+		t.Errorf("State() = %s, want %s", got, Status_CONFIGURING)
+	}
+}
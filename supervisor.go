@@ -0,0 +1,190 @@
+package nerf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NebulaProcess supervises Nebula started on behalf of nerf-api
+type NebulaProcess struct {
+	mutex    sync.Mutex
+	config   string
+	pid      int32
+	cancel   context.CancelFunc
+	stopping bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NebulaStart starts Nebula instance with the config, supervised by nerf-api
+func NebulaStart(config string) (*NebulaProcess, error) {
+	p := &NebulaProcess{
+		config: config,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	p.mutex.Lock()
+	stream, err := p.start()
+	p.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	go p.supervise(stream)
+
+	return p, nil
+}
+
+// start starts Nebula, must be called with mutex locked
+func (p *NebulaProcess) start() (Helper_StartNebulaClient, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream, err := Cfg.Helper.StartNebula(ctx, &HelperNebula{Config: p.config})
+	if err != nil {
+		cancel()
+		return nil, helperError(err)
+	}
+
+	// The first message is sent once Nebula is running.
+	output, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return nil, helperError(err)
+	}
+
+	p.pid = output.Pid
+	p.cancel = cancel
+
+	Cfg.Logger.Debug("started Nebula", zap.Int32("Pid", output.Pid))
+
+	return stream, nil
+}
+
+// wait forwards the output of Nebula to the logger until it exits
+func (p *NebulaProcess) wait(stream Helper_StartNebulaClient) error {
+	for {
+		output, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("lost connection to nerf-helper: %s", status.Convert(err).Message())
+		}
+
+		if output.Exited {
+			if output.Error != "" {
+				return errors.New(output.Error)
+			}
+			return nil
+		}
+
+		Cfg.Logger.Info("nebula", zap.String("Stream", output.Stream), zap.String("Output", output.Line))
+	}
+}
+
+// supervise restarts Nebula with backoff, unless it's being stopped
+func (p *NebulaProcess) supervise(stream Helper_StartNebulaClient) {
+	defer close(p.done)
+
+	restarts := 0
+	backoff := time.Second
+
+	for {
+		started := time.Now()
+		err := p.wait(stream)
+
+		p.mutex.Lock()
+		stopping := p.stopping
+		p.cancel()
+		p.mutex.Unlock()
+		if stopping {
+			return
+		}
+
+		if err == nil {
+			err = fmt.Errorf("exited unexpectedly")
+		}
+		Cfg.Logger.Error("Nebula stopped", zap.Int("Restarts", restarts), zap.Error(err))
+
+		if time.Since(started) > time.Minute {
+			restarts = 0
+			backoff = time.Second
+		}
+		if restarts >= Cfg.NebulaMaxRestarts {
+			go nebulaFailed(p, err)
+			return
+		}
+		restarts++
+
+		Cfg.Events.Publish("", NewEvent(
+			Event_NEBULA_RESTARTED,
+			fmt.Sprintf("Nebula stopped unexpectedly, restarting in %s", backoff),
+		))
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+
+		// Stop can't slip in between the check and the restart.
+		p.mutex.Lock()
+		if p.stopping {
+			p.mutex.Unlock()
+			return
+		}
+		stream, err = p.start()
+		p.mutex.Unlock()
+		if err != nil {
+			Cfg.Logger.Error("can't restart Nebula", zap.Error(err))
+			go nebulaFailed(p, err)
+			return
+		}
+	}
+}
+
+// Stop stops Nebula with SIGTERM, and SIGKILL after timeout
+func (p *NebulaProcess) Stop(timeout time.Duration) error {
+	p.mutex.Lock()
+	if p.stopping {
+		p.mutex.Unlock()
+		<-p.done
+		return nil
+	}
+	p.stopping = true
+	close(p.stop)
+	pid := p.pid
+	cancel := p.cancel
+	p.mutex.Unlock()
+
+	ctx, cancelStop := context.WithTimeout(context.Background(), timeout+10*time.Second)
+	defer cancelStop()
+
+	_, err := Cfg.Helper.StopNebula(ctx, &HelperNebulaStop{Pid: pid, TimeoutMs: timeout.Milliseconds()})
+	if status.Code(err) == codes.NotFound {
+		// Already exited, e.g. while waiting for restart.
+		err = nil
+	} else if err != nil {
+		// nerf-helper stops Nebula anyway once its stream is cancelled.
+		cancel()
+	}
+	<-p.done
+
+	return helperError(err)
+}
+
+// Pid returns the process ID of the running Nebula
+func (p *NebulaProcess) Pid() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return int(p.pid)
+}
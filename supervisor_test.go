@@ -0,0 +1,244 @@
+package nerf
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeHelper runs fake Nebula processes, which exit when stopped or told so
+type fakeHelper struct {
+	HelperClient
+
+	mutex     sync.Mutex
+	pid       int32
+	processes map[int32]chan *HelperNebulaOutput
+	stopped   []int32
+	// starting and release hold StartNebula until released, if set
+	starting chan struct{}
+	release  chan struct{}
+	// startErr fails StartNebula, if set
+	startErr error
+	routes   map[string]bool
+}
+
+// fakeNebulaStream streams the output of a fake Nebula process
+type fakeNebulaStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	output chan *HelperNebulaOutput
+}
+
+func (s *fakeNebulaStream) Recv() (*HelperNebulaOutput, error) {
+	select {
+	case output := <-s.output:
+		return output, nil
+	case <-s.ctx.Done():
+		return nil, status.FromContextError(s.ctx.Err()).Err()
+	}
+}
+
+func newFakeHelper() *fakeHelper {
+	return &fakeHelper{processes: map[int32]chan *HelperNebulaOutput{}, routes: map[string]bool{}}
+}
+
+func (h *fakeHelper) StartNebula(ctx context.Context, in *HelperNebula, opts ...grpc.CallOption) (Helper_StartNebulaClient, error) {
+	h.mutex.Lock()
+	starting, release := h.starting, h.release
+	h.mutex.Unlock()
+	if starting != nil {
+		starting <- struct{}{}
+		<-release
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.startErr != nil {
+		return nil, h.startErr
+	}
+
+	h.pid++
+	output := make(chan *HelperNebulaOutput, 16)
+	output <- &HelperNebulaOutput{Pid: h.pid}
+	h.processes[h.pid] = output
+
+	return &fakeNebulaStream{ctx: ctx, output: output}, nil
+}
+
+func (h *fakeHelper) StopNebula(ctx context.Context, in *HelperNebulaStop, opts ...grpc.CallOption) (*empty.Empty, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	output, ok := h.processes[in.Pid]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "not running")
+	}
+	delete(h.processes, in.Pid)
+	h.stopped = append(h.stopped, in.Pid)
+	output <- &HelperNebulaOutput{Exited: true}
+
+	return &empty.Empty{}, nil
+}
+
+func (h *fakeHelper) AddRoute(ctx context.Context, in *HelperRoute, opts ...grpc.CallOption) (*empty.Empty, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.routes[in.RemoteIP] = true
+
+	return &empty.Empty{}, nil
+}
+
+func (h *fakeHelper) DeleteRoute(ctx context.Context, in *HelperRoute, opts ...grpc.CallOption) (*empty.Empty, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.routes, in.RemoteIP)
+
+	return &empty.Empty{}, nil
+}
+
+func (h *fakeHelper) SetNameServers(ctx context.Context, in *HelperNameServers, opts ...grpc.CallOption) (*empty.Empty, error) {
+	return &empty.Empty{}, nil
+}
+
+func (h *fakeHelper) RevertNameServers(ctx context.Context, in *HelperRoute, opts ...grpc.CallOption) (*empty.Empty, error) {
+	return &empty.Empty{}, nil
+}
+
+// exit makes the running Nebula exit unexpectedly
+func (h *fakeHelper) exit(pid int32) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	output := h.processes[pid]
+	delete(h.processes, pid)
+	output <- &HelperNebulaOutput{Exited: true, Error: "exit status 1"}
+}
+
+// running returns the number of fake Nebula processes running
+func (h *fakeHelper) running() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return len(h.processes)
+}
+
+func useFakeHelper(t *testing.T) *fakeHelper {
+	helper := newFakeHelper()
+	previous := Cfg.Helper
+	Cfg.Helper = helper
+	t.Cleanup(func() {
+		Cfg.Helper = previous
+	})
+
+	return helper
+}
+
+func TestNebulaProcessStop(t *testing.T) {
+	helper := useFakeHelper(t)
+
+	p, err := NebulaStart("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Pid() != 1 {
+		t.Errorf("Pid() = %d, want 1", p.Pid())
+	}
+
+	if err := p.Stop(time.Second); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	helper.mutex.Lock()
+	stopped := helper.stopped
+	helper.mutex.Unlock()
+	if helper.running() != 0 || len(stopped) != 1 || stopped[0] != 1 {
+		t.Errorf("running %d, stopped %v, want Nebula 1 stopped", helper.running(), stopped)
+	}
+
+	// Stopping again only waits for the supervisor.
+	if err := p.Stop(time.Second); err != nil {
+		t.Errorf("second Stop() error = %v", err)
+	}
+}
+
+func TestNebulaProcessRestart(t *testing.T) {
+	helper := useFakeHelper(t)
+	events := Cfg.Events.Subscribe("")
+	defer Cfg.Events.Unsubscribe(events)
+
+	p, err := NebulaStart("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop(time.Second)
+
+	helper.exit(1)
+
+	select {
+	case event := <-events:
+		if event.Type != Event_NEBULA_RESTARTED {
+			t.Errorf("event = %s, want %s", event.Type, Event_NEBULA_RESTARTED)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("restart not reported")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Pid() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Nebula not restarted, Pid() = %d", p.Pid())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNebulaProcessStopDuringRestart(t *testing.T) {
+	helper := useFakeHelper(t)
+
+	p, err := NebulaStart("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	helper.mutex.Lock()
+	helper.starting = make(chan struct{})
+	helper.release = make(chan struct{})
+	helper.mutex.Unlock()
+
+	helper.exit(1)
+
+	// The supervisor is restarting Nebula after the backoff.
+	select {
+	case <-helper.starting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Nebula not restarted")
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- p.Stop(time.Second)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(helper.release)
+
+	// Stop must not miss the Nebula being started.
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Stop() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() did not return")
+	}
+	if n := helper.running(); n != 0 {
+		t.Errorf("%d Nebula processes left running", n)
+	}
+}
@@ -0,0 +1,99 @@
+package nerf
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"go.uber.org/zap"
+)
+
+// storedToken is the OAuth token persisted between sessions of the GUI
+type storedToken struct {
+	Login       string
+	AccessToken string
+}
+
+// TokenFile returns the path of the stored OAuth token of the current user
+func TokenFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(dir, "nerf", "token.json"), nil
+}
+
+// SaveToken stores Cfg.Token and Cfg.Login readable by the current user only
+func SaveToken() error {
+	file, err := TokenFile()
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(path.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(storedToken{Login: Cfg.Login, AccessToken: Cfg.Token})
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(file, data, 0600)
+}
+
+// LoadToken restores Cfg.Token and Cfg.Login from the stored OAuth token
+func LoadToken() bool {
+	file, err := TokenFile()
+	if err != nil {
+		return false
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return false
+	}
+
+	// Don't trust the token if anybody else could read or replace it.
+	if info.Mode().Perm()&0077 != 0 {
+		Cfg.Logger.Warn("stored token is accessible by other users, ignoring it",
+			zap.String("Path", file),
+			zap.String("Mode", info.Mode().String()))
+		return false
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false
+	}
+
+	var token storedToken
+	if err := json.Unmarshal(data, &token); err != nil || token.AccessToken == "" || token.Login == "" {
+		Cfg.Logger.Warn("stored token is not valid, ignoring it", zap.String("Path", file))
+		return false
+	}
+
+	Cfg.Token = token.AccessToken
+	Cfg.Login = token.Login
+
+	return true
+}
+
+// DeleteToken removes the stored OAuth token, thus signs the user out
+func DeleteToken() error {
+	Cfg.Token = ""
+	Cfg.Login = ""
+
+	file, err := TokenFile()
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
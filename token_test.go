@@ -0,0 +1,123 @@
+package nerf
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// useConfigDir points the user config directory to a temporary one
+func useConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"XDG_CONFIG_HOME", "HOME"} {
+		previous, ok := os.LookupEnv(name)
+		os.Setenv(name, dir)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+	t.Cleanup(func() {
+		Cfg.Login = ""
+		Cfg.Token = ""
+	})
+}
+
+func TestSaveToken(t *testing.T) {
+	useConfigDir(t)
+	Cfg.Login = "alice"
+	Cfg.Token = "secret"
+
+	if err := SaveToken(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, _ := TokenFile()
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("token file mode = %o, want 600", mode)
+	}
+	dirInfo, err := os.Stat(path.Dir(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := dirInfo.Mode().Perm(); mode != 0700 {
+		t.Errorf("token directory mode = %o, want 700", mode)
+	}
+
+	Cfg.Login = ""
+	Cfg.Token = ""
+	if !LoadToken() {
+		t.Fatal("LoadToken() = false, want the saved token")
+	}
+	if Cfg.Login != "alice" || Cfg.Token != "secret" {
+		t.Errorf("loaded %q, %q, want alice, secret", Cfg.Login, Cfg.Token)
+	}
+
+	if err := DeleteToken(); err != nil {
+		t.Fatal(err)
+	}
+	if Cfg.Login != "" || Cfg.Token != "" || LoadToken() {
+		t.Error("token still usable after DeleteToken()")
+	}
+	if err := DeleteToken(); err != nil {
+		t.Errorf("DeleteToken() without a stored token error = %v", err)
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		mode os.FileMode
+		want bool
+	}{
+		{name: "valid", data: `{"Login": "alice", "AccessToken": "secret"}`, mode: 0600, want: true},
+		{name: "readable by owner only", data: `{"Login": "alice", "AccessToken": "secret"}`, mode: 0400, want: true},
+		{name: "readable by group", data: `{"Login": "alice", "AccessToken": "secret"}`, mode: 0640, want: false},
+		{name: "writable by others", data: `{"Login": "alice", "AccessToken": "secret"}`, mode: 0602, want: false},
+		{name: "malformed", data: `{"Login": "alice"`, mode: 0600, want: false},
+		{name: "no token", data: `{"Login": "alice"}`, mode: 0600, want: false},
+		{name: "no login", data: `{"AccessToken": "secret"}`, mode: 0600, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfigDir(t)
+
+			file, _ := TokenFile()
+			if err := os.MkdirAll(path.Dir(file), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(file, []byte(tt.data), tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			// The mode is not subject to umask.
+			if err := os.Chmod(file, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := LoadToken(); got != tt.want {
+				t.Errorf("LoadToken() = %t, want %t", got, tt.want)
+			}
+			if !tt.want && (Cfg.Login != "" || Cfg.Token != "") {
+				t.Errorf("rejected token loaded as %q, %q", Cfg.Login, Cfg.Token)
+			}
+		})
+	}
+}
+
+func TestLoadTokenMissing(t *testing.T) {
+	useConfigDir(t)
+
+	if LoadToken() {
+		t.Error("LoadToken() = true without a stored token")
+	}
+}
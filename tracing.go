@@ -0,0 +1,219 @@
+package nerf
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDKey is the gRPC metadata key used to pass request IDs between processes
+const requestIDKey = "x-request-id"
+
+type requestIDContextKey struct{}
+
+// InitTracing sets up OpenTelemetry tracing, the returned function flushes pending spans
+func InitTracing(serviceName string, otlpEndpoint string) (func(context.Context) error, error) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+		)),
+	}
+
+	if otlpEndpoint != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		exporter, err := otlptracegrpc.New(
+			ctx,
+			otlptracegrpc.WithEndpoint(otlpEndpoint),
+			otlptracegrpc.WithInsecure(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span for the given phase of connection
+func StartSpan(ctx context.Context, phase string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("nerf.phase", phase))
+	return otel.Tracer("github.com/ton31337/nerf").Start(ctx, phase, trace.WithAttributes(attrs...))
+}
+
+// EndpointAttribute span attribute for gRPC endpoint
+func EndpointAttribute(e *Endpoint) attribute.KeyValue {
+	return attribute.String("nerf.endpoint", e.RemoteHost)
+}
+
+// LoginAttribute span attribute for Github login
+func LoginAttribute(login string) attribute.KeyValue {
+	return attribute.String("nerf.login", login)
+}
+
+// RequestID returns request ID stored in the context
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDContextKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// WithRequestID stores a new request ID in the context unless it already has one
+func WithRequestID(ctx context.Context) context.Context {
+	if RequestID(ctx) != "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDContextKey{}, uuid.NewString())
+}
+
+// TraceFields returns trace and request IDs as log fields
+func TraceFields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields,
+			zap.String("TraceID", sc.TraceID().String()),
+			zap.String("SpanID", sc.SpanID().String()))
+	}
+
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, zap.String("RequestID", id))
+	}
+
+	return fields
+}
+
+// GRPCDialOptions options for gRPC clients with trace context propagation
+func GRPCDialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			otelgrpc.UnaryClientInterceptor(),
+			requestIDUnaryClientInterceptor,
+		),
+		grpc.WithChainStreamInterceptor(
+			otelgrpc.StreamClientInterceptor(),
+			requestIDStreamClientInterceptor,
+		),
+	}
+}
+
+// GRPCServerOptions options for gRPC servers with trace context propagation
+func GRPCServerOptions(logger *zap.Logger) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			otelgrpc.UnaryServerInterceptor(),
+			requestIDUnaryServerInterceptor(logger),
+		),
+		grpc.ChainStreamInterceptor(
+			otelgrpc.StreamServerInterceptor(),
+			requestIDStreamServerInterceptor(logger),
+		),
+	}
+}
+
+func outgoingRequestID(ctx context.Context) context.Context {
+	ctx = WithRequestID(ctx)
+	return metadata.AppendToOutgoingContext(ctx, requestIDKey, RequestID(ctx))
+}
+
+func incomingRequestID(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(requestIDKey); len(ids) > 0 && ids[0] != "" {
+			return context.WithValue(ctx, requestIDContextKey{}, ids[0])
+		}
+	}
+	return WithRequestID(ctx)
+}
+
+func requestIDUnaryClientInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+}
+
+func requestIDStreamClientInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+}
+
+func requestIDUnaryServerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx = incomingRequestID(ctx)
+		start := time.Now()
+		response, err := handler(ctx, req)
+		logger.With(TraceFields(ctx)...).Debug("gRPC request",
+			zap.String("Method", info.FullMethod),
+			zap.Duration("Duration", time.Since(start)),
+			zap.Error(err))
+		return response, err
+	}
+}
+
+type requestIDServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestIDServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func requestIDStreamServerInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := incomingRequestID(ss.Context())
+		start := time.Now()
+		err := handler(srv, &requestIDServerStream{ServerStream: ss, ctx: ctx})
+		logger.With(TraceFields(ctx)...).Debug("gRPC stream",
+			zap.String("Method", info.FullMethod),
+			zap.Duration("Duration", time.Since(start)),
+			zap.Error(err))
+		return err
+	}
+}
@@ -0,0 +1,125 @@
+package nerf
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// tracedServer is a gRPC server with the options of nerf-server, the context of
+// every request is sent to the returned channel
+func tracedServer(t *testing.T) (*grpc.ClientConn, chan context.Context) {
+	requests := make(chan context.Context, 1)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := append(GRPCServerOptions(zap.NewNop()), grpc.ChainUnaryInterceptor(func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		requests <- ctx
+		return handler(ctx, req)
+	}))
+	server := grpc.NewServer(options...)
+	healthpb.RegisterHealthServer(server, NewServerConfig().Health)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), GRPCDialOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return conn, requests
+}
+
+func TestWithRequestID(t *testing.T) {
+	ctx := WithRequestID(context.Background())
+	id := RequestID(ctx)
+	if id == "" {
+		t.Fatal("WithRequestID() did not set a request ID")
+	}
+
+	if got := RequestID(WithRequestID(ctx)); got != id {
+		t.Errorf("RequestID() = %s, want %s kept", got, id)
+	}
+	if other := RequestID(WithRequestID(context.Background())); other == id {
+		t.Errorf("request ID %s reused", id)
+	}
+}
+
+func TestTraceFields(t *testing.T) {
+	if _, err := InitTracing("nerf-test", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, span := StartSpan(WithRequestID(context.Background()), "test")
+	defer span.End()
+
+	fields := map[string]string{}
+	for _, field := range TraceFields(ctx) {
+		fields[field.Key] = field.String
+	}
+
+	want := map[string]string{
+		"TraceID":   span.SpanContext().TraceID().String(),
+		"SpanID":    span.SpanContext().SpanID().String(),
+		"RequestID": RequestID(ctx),
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("TraceFields() %s = %q, want %q", key, fields[key], value)
+		}
+	}
+
+	if fields := TraceFields(context.Background()); len(fields) != 0 {
+		t.Errorf("TraceFields() without trace = %v, want none", fields)
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	if _, err := InitTracing("nerf-test", ""); err != nil {
+		t.Fatal(err)
+	}
+	conn, requests := tracedServer(t)
+
+	ctx, span := StartSpan(WithRequestID(context.Background()), "test")
+	defer span.End()
+
+	_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverCtx := <-requests
+	if got, want := RequestID(serverCtx), RequestID(ctx); got != want {
+		t.Errorf("request ID on the server = %q, want %q", got, want)
+	}
+	got := trace.SpanContextFromContext(serverCtx).TraceID()
+	if want := span.SpanContext().TraceID(); got != want {
+		t.Errorf("trace ID on the server = %s, want %s", got, want)
+	}
+
+	// Requests without a request ID get a new one.
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if id := RequestID(<-requests); id == "" || id == RequestID(ctx) {
+		t.Errorf("request ID on the server = %q, want a new one", id)
+	}
+}
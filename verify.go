@@ -0,0 +1,228 @@
+package nerf
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// NebulaSHA256 compile-time derived from -X github.com/ton31337/nerf.NebulaSHA256
+var NebulaSHA256 string
+
+// NebulaSigningKey compile-time derived from -X github.com/ton31337/nerf.NebulaSigningKey
+var NebulaSigningKey string
+
+// nebulaSignature is the path of the detached signature of the installed Nebula binary
+func nebulaSignature() string {
+	return path.Join(NebulaDir(), "nebula.sig")
+}
+
+// nebulaChecksum is the path of the checksum the installed Nebula binary was verified against
+func nebulaChecksum() string {
+	return path.Join(NebulaDir(), "nebula.sha256")
+}
+
+// nebulaVerify checks the Nebula binary against the configured checksums and signature
+func nebulaVerify(binary []byte, checksum string, signature []byte) error {
+	return verifyNebulaBinary(binary, []string{Cfg.NebulaSHA256, checksum}, Cfg.NebulaSigningKey, signature)
+}
+
+// verifyNebulaBinary checks the binary against the checksums and the signature
+func verifyNebulaBinary(binary []byte, checksums []string, key string, signature []byte) error {
+	verified := false
+
+	sum := sha256.Sum256(binary)
+	for _, expected := range checksums {
+		if expected == "" {
+			continue
+		}
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), expected) {
+			return fmt.Errorf("checksum mismatch of Nebula: expected %s, got %x", expected, sum)
+		}
+		verified = true
+	}
+
+	if key != "" {
+		publicKey, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid signing key for Nebula")
+		}
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil || !ed25519.Verify(publicKey, binary, sig) {
+			return fmt.Errorf("invalid signature of Nebula")
+		}
+		verified = true
+	}
+
+	if !verified {
+		return fmt.Errorf("neither checksum nor signing key is configured for Nebula")
+	}
+
+	return nil
+}
+
+// helperNebulaVerify trusts only the checksum and the signing key compiled in
+func helperNebulaVerify(binary []byte, signature []byte) error {
+	if NebulaSHA256 == "" && NebulaSigningKey == "" {
+		return fmt.Errorf("nerf-helper is built without NebulaSHA256 and NebulaSigningKey")
+	}
+
+	return verifyNebulaBinary(binary, []string{NebulaSHA256}, NebulaSigningKey, signature)
+}
+
+// NebulaVerify checks the installed Nebula binary before it's executed
+func NebulaVerify() error {
+	binary, err := ioutil.ReadFile(NebulaExecutable())
+	if err != nil {
+		return err
+	}
+
+	var recorded []byte
+	if Cfg.NebulaSHA256 == "" && Cfg.NebulaSigningKey == "" {
+		if recorded, err = ioutil.ReadFile(nebulaChecksum()); err != nil {
+			return err
+		}
+	}
+
+	var signature []byte
+	if Cfg.NebulaSigningKey != "" {
+		if signature, err = ioutil.ReadFile(nebulaSignature()); err != nil {
+			return err
+		}
+	}
+
+	return nebulaVerifyInstalled(binary, strings.TrimSpace(string(recorded)), signature)
+}
+
+// nebulaVerifyInstalled trusts the recorded checksum only if nothing is pinned
+func nebulaVerifyInstalled(binary []byte, recorded string, signature []byte) error {
+	if Cfg.NebulaSHA256 != "" || Cfg.NebulaSigningKey != "" {
+		return nebulaVerify(binary, "", signature)
+	}
+
+	return nebulaVerify(binary, recorded, signature)
+}
+
+// nebulaFetch downloads url with the size limited to 256MB
+func nebulaFetch(ctx context.Context, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed download %s: %s", url, resp.Status)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 256<<20))
+}
+
+// nebulaDownloadVerified downloads Nebula binary with its signature, and verifies them
+func nebulaDownloadVerified(ctx context.Context, url string, checksum string) ([]byte, []byte, error) {
+	binary, err := nebulaFetch(ctx, url)
+	if err != nil {
+		Cfg.Logger.Error("can't download Nebula binary",
+			zap.String("Url", url),
+			zap.Error(err))
+		return nil, nil, err
+	}
+
+	var signature []byte
+	if Cfg.NebulaSigningKey != "" {
+		if signature, err = nebulaFetch(ctx, url+".sig"); err != nil {
+			Cfg.Logger.Error("can't download Nebula signature",
+				zap.String("Url", url+".sig"),
+				zap.Error(err))
+			return nil, nil, err
+		}
+	}
+
+	if err = nebulaVerify(binary, checksum, signature); err != nil {
+		Cfg.Logger.Error("downloaded Nebula binary is not valid",
+			zap.String("Url", url),
+			zap.Error(err))
+		return nil, nil, err
+	}
+
+	return binary, signature, nil
+}
+
+// nebulaInstall puts the verified Nebula binary from url in place
+func nebulaInstall(ctx context.Context, url string, checksum string) error {
+	if err := os.MkdirAll(NebulaDir(), 0755); err != nil {
+		return err
+	}
+
+	binary, signature, err := nebulaDownloadVerified(ctx, url, checksum)
+	if err != nil {
+		return err
+	}
+
+	if signature != nil {
+		if err = writeFileAtomic(nebulaSignature(), signature, 0644); err != nil {
+			return err
+		}
+	}
+	if err = writeFileAtomic(nebulaChecksum(), []byte(checksum+"\n"), 0644); err != nil {
+		return err
+	}
+	if err = writeFileAtomic(NebulaExecutable(), binary, 0755); err != nil {
+		Cfg.Logger.Error("can't write Nebula binary",
+			zap.String("Path", NebulaExecutable()),
+			zap.Error(err))
+		return err
+	}
+
+	Cfg.Logger.Info("installed Nebula", zap.String("Url", url))
+
+	return nil
+}
+
+// NebulaDownload used to download Nebula binary
+func NebulaDownload(ctx context.Context) error {
+	if Cfg.NebulaManifestURL != "" {
+		if err := nebulaCheckManifestKeys(); err != nil {
+			return err
+		}
+
+		manifest, err := NebulaFetchManifest(ctx, Cfg.NebulaManifestURL)
+		if err != nil {
+			// Keep using the installed Nebula while the manifest is unreachable.
+			if NebulaVerify() == nil {
+				Cfg.Logger.Warn("can't fetch Nebula manifest",
+					zap.String("Url", Cfg.NebulaManifestURL),
+					zap.Error(err))
+				return nil
+			}
+			return err
+		}
+		return NebulaUpgrade(ctx, manifest)
+	}
+
+	if err := NebulaVerify(); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		Cfg.Logger.Warn("installed Nebula binary is not valid, downloading",
+			zap.String("Path", NebulaExecutable()),
+			zap.Error(err))
+	}
+
+	return nebulaInstall(ctx, Cfg.NebulaDownloadURL, Cfg.NebulaSHA256)
+}
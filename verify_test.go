@@ -0,0 +1,234 @@
+package nerf
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveNebula serves the binary and its signature as a Nebula release
+func serveNebula(t *testing.T, binary []byte, signature []byte) string {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/nebula", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(binary)
+	})
+	mux.HandleFunc("/nebula.sig", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(signature)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server.URL + "/nebula"
+}
+
+// pinNebula sets the pinned checksum and signing key for the test
+func pinNebula(t *testing.T, checksum string, key string) {
+	previousChecksum, previousKey := Cfg.NebulaSHA256, Cfg.NebulaSigningKey
+	Cfg.NebulaSHA256, Cfg.NebulaSigningKey = checksum, key
+	t.Cleanup(func() {
+		Cfg.NebulaSHA256, Cfg.NebulaSigningKey = previousChecksum, previousKey
+	})
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestNebulaVerify(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(public)
+
+	binary := []byte("nebula binary")
+	tampered := []byte("tampered nebula binary")
+	signature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, binary)))
+	otherSignature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, tampered)))
+
+	tests := []struct {
+		name      string
+		binary    []byte
+		signature []byte
+		pinned    string
+		key       string
+		wantErr   bool
+	}{
+		{name: "good binary, pinned checksum", binary: binary, pinned: sha256Hex(binary)},
+		{name: "good binary, uppercase checksum", binary: binary, pinned: strings.ToUpper(sha256Hex(binary))},
+		{name: "good binary, signature", binary: binary, signature: signature, key: key},
+		{name: "good binary, both", binary: binary, signature: signature, pinned: sha256Hex(binary), key: key},
+		{name: "tampered binary, pinned checksum", binary: tampered, pinned: sha256Hex(binary), wantErr: true},
+		{name: "tampered binary, signature", binary: tampered, signature: signature, key: key, wantErr: true},
+		{
+			// Both are checked, if both are configured.
+			name:      "tampered binary, signed but not pinned",
+			binary:    tampered,
+			signature: otherSignature,
+			pinned:    sha256Hex(binary),
+			key:       key,
+			wantErr:   true,
+		},
+		{name: "bad signature", binary: binary, signature: otherSignature, key: key, wantErr: true},
+		{name: "malformed signature", binary: binary, signature: []byte("signature"), key: key, wantErr: true},
+		{name: "malformed key", binary: binary, signature: signature, key: "key", wantErr: true},
+		{name: "nothing to verify against", binary: binary, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinNebula(t, tt.pinned, tt.key)
+
+			err := nebulaVerify(tt.binary, "", tt.signature)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("nebulaVerify() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNebulaDownloadVerified(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(public)
+
+	binary := []byte("nebula binary")
+	tampered := []byte("tampered nebula binary")
+	signature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, binary)))
+	otherSignature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, tampered)))
+
+	tests := []struct {
+		name      string
+		served    []byte
+		signature []byte
+		pinned    string
+		key       string
+		checksum  string
+		wantErr   bool
+	}{
+		{name: "good binary, pinned checksum", served: binary, pinned: sha256Hex(binary)},
+		{name: "good binary, release checksum", served: binary, checksum: sha256Hex(binary)},
+		{name: "good binary, signature", served: binary, signature: signature, key: key},
+		{name: "tampered binary, pinned checksum", served: tampered, pinned: sha256Hex(binary), wantErr: true},
+		{
+			// The checksum of the release can't override the pinned one.
+			name:     "tampered binary, release checksum",
+			served:   tampered,
+			pinned:   sha256Hex(binary),
+			checksum: sha256Hex(tampered),
+			wantErr:  true,
+		},
+		{name: "tampered binary, signature", served: tampered, signature: signature, key: key, wantErr: true},
+		{name: "bad signature", served: binary, signature: otherSignature, key: key, wantErr: true},
+		{name: "malformed signature", served: binary, signature: []byte("signature"), key: key, wantErr: true},
+		{name: "nothing to verify against", served: binary, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinNebula(t, tt.pinned, tt.key)
+			url := serveNebula(t, tt.served, tt.signature)
+
+			got, _, err := nebulaDownloadVerified(context.Background(), url, tt.checksum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("nebulaDownloadVerified() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil && string(got) != string(tt.served) {
+				t.Errorf("nebulaDownloadVerified() = %q, want %q", got, tt.served)
+			}
+		})
+	}
+}
+
+func TestNebulaVerifyInstalled(t *testing.T) {
+	binary := []byte("nebula binary")
+	tampered := []byte("tampered nebula binary")
+
+	tests := []struct {
+		name     string
+		binary   []byte
+		pinned   string
+		recorded string
+		wantErr  bool
+	}{
+		{name: "pinned", binary: binary, pinned: sha256Hex(binary)},
+		{name: "recorded", binary: binary, recorded: sha256Hex(binary)},
+		{name: "recorded mismatch", binary: tampered, recorded: sha256Hex(binary), wantErr: true},
+		{
+			// Recording the checksum of a replaced binary does not get it past the pinned one.
+			name:     "pinned wins over recorded",
+			binary:   tampered,
+			pinned:   sha256Hex(binary),
+			recorded: sha256Hex(tampered),
+			wantErr:  true,
+		},
+		{name: "nothing to verify against", binary: binary, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinNebula(t, tt.pinned, "")
+
+			err := nebulaVerifyInstalled(tt.binary, tt.recorded, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("nebulaVerifyInstalled() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHelperNebulaVerify(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(public)
+
+	binary := []byte("nebula binary")
+	tampered := []byte("tampered nebula binary")
+	signature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, binary)))
+
+	tests := []struct {
+		name      string
+		binary    []byte
+		checksum  string
+		key       string
+		signature []byte
+		wantErr   bool
+	}{
+		{name: "checksum", binary: binary, checksum: sha256Hex(binary)},
+		{name: "signature", binary: binary, key: key, signature: signature},
+		{name: "tampered", binary: tampered, checksum: sha256Hex(binary), wantErr: true},
+		{name: "tampered signature", binary: tampered, key: key, signature: signature, wantErr: true},
+		{name: "nothing compiled in", binary: binary, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checksum, signingKey := NebulaSHA256, NebulaSigningKey
+			defer func() {
+				NebulaSHA256, NebulaSigningKey = checksum, signingKey
+			}()
+			NebulaSHA256, NebulaSigningKey = tt.checksum, tt.key
+
+			// The configuration of nerf-api is never trusted by nerf-helper.
+			pinNebula(t, sha256Hex(tt.binary), "")
+
+			err := helperNebulaVerify(tt.binary, tt.signature)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("helperNebulaVerify() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}